@@ -6,15 +6,25 @@ import (
 	"github.com/go-pkg-org/gopkg/internal/archive"
 	"github.com/go-pkg-org/gopkg/internal/build"
 	"github.com/go-pkg-org/gopkg/internal/cache"
+	"github.com/go-pkg-org/gopkg/internal/changelog"
 	"github.com/go-pkg-org/gopkg/internal/config"
+	"github.com/go-pkg-org/gopkg/internal/diff"
+	"github.com/go-pkg-org/gopkg/internal/httpclient"
+	"github.com/go-pkg-org/gopkg/internal/inspect"
+	"github.com/go-pkg-org/gopkg/internal/lint"
 	make2 "github.com/go-pkg-org/gopkg/internal/make"
+	"github.com/go-pkg-org/gopkg/internal/patch"
+	"github.com/go-pkg-org/gopkg/internal/pkg"
 	"github.com/go-pkg-org/gopkg/internal/sign"
 	"github.com/go-pkg-org/gopkg/internal/upload"
+	"github.com/go-pkg-org/gopkg/internal/watch"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/urfave/cli/v2"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 func main() {
@@ -34,14 +44,87 @@ func main() {
 			{
 				Name:      "make",
 				Usage:     "create a new package from import-path",
-				ArgsUsage: "import-path",
+				ArgsUsage: "import-path|control-path",
 				Action:    execMake,
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "update",
+						Usage: "update the given control directory to the latest upstream version",
+					},
+					&cli.BoolFlag{
+						Name:  "recursive",
+						Usage: "also create the control directories of the dependencies not packaged yet",
+					},
+				},
 			},
 			{
 				Name:      "build",
 				Usage:     "build a package from control directory/package",
 				ArgsUsage: "control-path",
 				Action:    execBuild,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "compression",
+						Usage: "packages compression (none, gzip or zstd)",
+						Value: string(pkg.NoCompression),
+					},
+				},
+			},
+			{
+				Name:  "changelog",
+				Usage: "manage the changelog of a control directory",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "add",
+						Usage:     "add a change to the latest release",
+						ArgsUsage: "message [control-path]",
+						Action:    execChangelogAdd,
+					},
+					{
+						Name:      "bump",
+						Usage:     "add a new packaging revision of the latest upstream version",
+						ArgsUsage: "[control-path]",
+						Action:    execChangelogBump,
+						Flags:     []cli.Flag{changelogMessageFlag},
+					},
+					{
+						Name:      "new-upstream",
+						Usage:     "add the initial release of a new upstream version",
+						ArgsUsage: "version [control-path]",
+						Action:    execChangelogNewUpstream,
+						Flags:     []cli.Flag{changelogMessageFlag},
+					},
+				},
+			},
+			{
+				Name:      "watch",
+				Usage:     "check if new upstream releases are available",
+				ArgsUsage: "[control-path...]",
+				Action:    execWatch,
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "output the results as JSON",
+					},
+				},
+			},
+			{
+				Name:  "patch",
+				Usage: "manage the patches applied on the sources of a control directory",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "new",
+						Usage:     "record the working tree changes into a new patch",
+						ArgsUsage: "name [control-path]",
+						Action:    execPatchNew,
+					},
+					{
+						Name:      "refresh",
+						Usage:     "update the topmost patch with the working tree changes",
+						ArgsUsage: "[control-path]",
+						Action:    execPatchRefresh,
+					},
+				},
 			},
 			{
 				Name:      "install",
@@ -71,6 +154,36 @@ func main() {
 				},
 				Action: execList,
 			},
+			{
+				Name:      "inspect",
+				Usage:     "show the content of a package file",
+				ArgsUsage: "pkg-path",
+				Action:    execInspect,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "extract",
+						Usage: "extract the package into given directory",
+					},
+				},
+			},
+			{
+				Name:      "diff",
+				Usage:     "show the differences between two packages",
+				ArgsUsage: "old-pkg new-pkg (file.pkg or alias@version)",
+				Action:    execDiff,
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "stat",
+						Usage: "only display a summary of the changes",
+					},
+				},
+			},
+			{
+				Name:      "lint",
+				Usage:     "check a control directory or package for common mistakes",
+				ArgsUsage: "control-path|pkg-path",
+				Action:    execLint,
+			},
 			{
 				Name:   "sign",
 				Usage:  "sign given package",
@@ -87,15 +200,78 @@ func main() {
 
 	if err := app.Run(os.Args); err != nil {
 		log.Err(err).Msg("error while running application")
-		os.Exit(1)
+		os.Exit(getExitCode(err))
+	}
+}
+
+// changelogMessageFlag set the changes of the release added to the changelog
+var changelogMessageFlag = &cli.StringSliceFlag{
+	Name:    "message",
+	Aliases: []string{"m"},
+	Usage:   "change description of the release (may be repeated)",
+}
+
+// Exit codes returned by gopkg so scripts can react to failures
+// 7 is reserved for invalid signatures, not verified by gopkg yet
+const (
+	exitError            = 1
+	exitNotFound         = 2
+	exitWrongTarget      = 3
+	exitAlreadyInstalled = 4
+	exitNetwork          = 5
+	exitCorruptPackage   = 6
+	exitUnsupported      = 8
+)
+
+// getExitCode returns the exit code matching given error
+func getExitCode(err error) int {
+	var netErr *archive.NetworkError
+
+	switch {
+	case errors.Is(err, pkg.ErrUnsupportedFormat):
+		return exitUnsupported
+	case errors.Is(err, pkg.ErrCorruptPackage):
+		return exitCorruptPackage
+	case errors.Is(err, pkg.ErrWrongTarget):
+		return exitWrongTarget
+	case errors.Is(err, pkg.ErrAlreadyInstalled):
+		return exitAlreadyInstalled
+	case errors.Is(err, pkg.ErrNotFound):
+		return exitNotFound
+	case errors.As(err, &netErr):
+		return exitNetwork
+	default:
+		return exitError
 	}
 }
 
 func execMake(c *cli.Context) error {
+	arcClient, err := getArchiveClient(c)
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("update") {
+		return make2.Update(getControlPath(c, 0), arcClient)
+	}
+
 	if !c.Args().Present() {
 		return errors.New("missing import-path")
 	}
-	return make2.Make(c.Args().First())
+
+	conf, err := config.Default()
+	if err != nil {
+		return err
+	}
+	httpClient, err := getHTTPClient(c, conf)
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("recursive") {
+		return make2.MakeRecursive(c.Args().First(), arcClient, httpClient, os.Stdout)
+	}
+	return make2.Make(c.Args().First(), arcClient, httpClient)
 }
 
 func execBuild(c *cli.Context) error {
@@ -109,7 +285,109 @@ func execBuild(c *cli.Context) error {
 		return err
 	}
 
-	return build.Build(absolutePath)
+	compression, err := pkg.ParseCompression(c.String("compression"))
+	if err != nil {
+		return err
+	}
+
+	return build.Build(absolutePath, compression)
+}
+
+func execChangelogAdd(c *cli.Context) error {
+	if !c.Args().Present() {
+		return errors.New("missing message")
+	}
+
+	return changelog.Add(getControlPath(c, 1), c.Args().First())
+}
+
+func execChangelogBump(c *cli.Context) error {
+	conf, err := config.Default()
+	if err != nil {
+		return err
+	}
+
+	version, err := changelog.Bump(getControlPath(c, 0), conf.GetMaintainerEntry(), c.StringSlice("message"))
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("version", version).Msg("Successfully added release")
+	return nil
+}
+
+func execChangelogNewUpstream(c *cli.Context) error {
+	if !c.Args().Present() {
+		return errors.New("missing version")
+	}
+
+	conf, err := config.Default()
+	if err != nil {
+		return err
+	}
+
+	version, err := changelog.NewUpstream(getControlPath(c, 1), c.Args().First(), conf.GetMaintainerEntry(),
+		c.StringSlice("message"))
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("version", version).Msg("Successfully added release")
+	return nil
+}
+
+func execWatch(c *cli.Context) error {
+	dirs := c.Args().Slice()
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+
+	conf, err := config.Default()
+	if err != nil {
+		return err
+	}
+	httpClient, err := getHTTPClient(c, conf)
+	if err != nil {
+		return err
+	}
+
+	var results []watch.Result
+	failed := 0
+	for _, dir := range dirs {
+		res := watch.Check(dir, httpClient)
+		if res.Error != "" {
+			failed++
+		}
+		results = append(results, res)
+	}
+
+	if err := watch.Write(os.Stdout, results, c.Bool("json")); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d control directories could not be checked", failed)
+	}
+
+	return nil
+}
+
+func execPatchNew(c *cli.Context) error {
+	if !c.Args().Present() {
+		return errors.New("missing name")
+	}
+
+	return patch.New(getControlPath(c, 1), c.Args().First())
+}
+
+func execPatchRefresh(c *cli.Context) error {
+	name, err := patch.Refresh(getControlPath(c, 0))
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("patch", name).Msg("Successfully refreshed patch")
+	return nil
 }
 
 func execInstall(c *cli.Context) error {
@@ -117,7 +395,7 @@ func execInstall(c *cli.Context) error {
 		return errors.New("missing pkg")
 	}
 
-	ca, err := getCache()
+	ca, err := getCache(c)
 	if err != nil {
 		return err
 	}
@@ -125,7 +403,7 @@ func execInstall(c *cli.Context) error {
 	if c.Bool("from-file") {
 		p, err := ca.InstallPkgFile(c.Args().First())
 		if err != nil {
-			return fmt.Errorf("error while installing package from file %s: %s", c.Args().First(), err)
+			return fmt.Errorf("error while installing package from file %s: %w", c.Args().First(), err)
 		}
 
 		log.Info().Str("package", p.Alias).Msg("Successfully installed package")
@@ -134,7 +412,7 @@ func execInstall(c *cli.Context) error {
 
 	p, err := ca.InstallPkg(c.Args().First())
 	if err != nil {
-		return fmt.Errorf("error while installing package %s: %s", c.Args().First(), err)
+		return fmt.Errorf("error while installing package %s: %w", c.Args().First(), err)
 	}
 	log.Info().Str("package", p.Alias).Msg("Successfully installed package")
 
@@ -146,13 +424,13 @@ func execRemove(c *cli.Context) error {
 		return errors.New("missing pkg-name")
 	}
 
-	ca, err := getCache()
+	ca, err := getCache(c)
 	if err != nil {
 		return err
 	}
 
 	if err := ca.RemovePkg(c.Args().First()); err != nil {
-		return fmt.Errorf("error while removing package %s: %s", c.Args().First(), err)
+		return fmt.Errorf("error while removing package %s: %w", c.Args().First(), err)
 	}
 
 	log.Info().Str("package", c.Args().First()).Msg("successfully removed package")
@@ -160,14 +438,14 @@ func execRemove(c *cli.Context) error {
 }
 
 func execList(c *cli.Context) error {
-	ca, err := getCache()
+	ca, err := getCache(c)
 	if err != nil {
 		return err
 	}
 
 	pkgs, err := ca.ListPackages(c.Bool("installed"))
 	if err != nil {
-		return fmt.Errorf("error while listing packages: %s", err)
+		return fmt.Errorf("error while listing packages: %w", err)
 	}
 
 	for _, pkg := range pkgs {
@@ -187,7 +465,80 @@ func execUpload(c *cli.Context) error {
 		return err
 	}
 
-	return upload.Upload(c.Args().First(), conf.UploadAddr)
+	httpClient, err := getHTTPClient(c, conf)
+	if err != nil {
+		return err
+	}
+
+	return upload.Upload(c.Args().First(), conf.UploadAddr, httpClient)
+}
+
+func execInspect(c *cli.Context) error {
+	if !c.Args().Present() {
+		return errors.New("missing pkg-path")
+	}
+
+	if dir := c.String("extract"); dir != "" {
+		return inspect.Extract(c.Args().First(), dir)
+	}
+
+	return inspect.Inspect(c.Args().First(), os.Stdout)
+}
+
+func execDiff(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return errors.New("missing old-pkg or new-pkg")
+	}
+
+	arcClient, err := getArchiveClient(c)
+	if err != nil {
+		return err
+	}
+
+	oldPkg, err := diff.Open(c.Args().Get(0), arcClient)
+	if err != nil {
+		return fmt.Errorf("error while opening package %s: %w", c.Args().Get(0), err)
+	}
+	newPkg, err := diff.Open(c.Args().Get(1), arcClient)
+	if err != nil {
+		return fmt.Errorf("error while opening package %s: %w", c.Args().Get(1), err)
+	}
+
+	return diff.Diff(oldPkg, newPkg, os.Stdout, c.Bool("stat"))
+}
+
+func execLint(c *cli.Context) error {
+	path := c.Args().First()
+	if path == "" {
+		path = "."
+	}
+
+	var issues []lint.Issue
+	if strings.HasSuffix(path, "."+pkg.FileExt) {
+		f, err := pkg.OpenFile(path)
+		if err != nil {
+			return err
+		}
+
+		if issues, err = lint.File(f, filepath.Base(path)); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if issues, err = lint.Directory(path); err != nil {
+			return err
+		}
+	}
+
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+
+	if lint.HasErrors(issues) {
+		return fmt.Errorf("%s has lint errors", path)
+	}
+
+	return nil
 }
 
 func execSign(c *cli.Context) error {
@@ -198,6 +549,15 @@ func execSign(c *cli.Context) error {
 	return sign.Sign(c.Args().First())
 }
 
+// getControlPath returns the control directory given as the n-th argument (default to the current one)
+func getControlPath(c *cli.Context, n int) string {
+	if path := c.Args().Get(n); path != "" {
+		return path
+	}
+
+	return "."
+}
+
 func getAbsolutePath(path string) (string, error) {
 	if !filepath.IsAbs(path) {
 		wd, err := os.Getwd()
@@ -210,16 +570,34 @@ func getAbsolutePath(path string) (string, error) {
 	return path, nil
 }
 
-func getCache() (cache.Cache, error) {
+func getCache(c *cli.Context) (cache.Cache, error) {
 	conf, err := config.Default()
 	if err != nil {
 		return nil, err
 	}
 
-	arcClient, err := archive.NewClient(conf.ArchiveAddr)
+	arcClient, err := getArchiveClient(c)
 	if err != nil {
 		return nil, err
 	}
 
 	return cache.NewCache(conf.CachePath, arcClient, conf)
 }
+
+func getArchiveClient(c *cli.Context) (archive.Client, error) {
+	conf, err := config.Default()
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient, err := getHTTPClient(c, conf)
+	if err != nil {
+		return nil, err
+	}
+
+	return archive.NewClient(conf.ArchiveAddr, httpClient, conf.DownloadDir)
+}
+
+func getHTTPClient(c *cli.Context, conf *config.Config) (*http.Client, error) {
+	return httpclient.New(conf.HTTP, fmt.Sprintf("gopkg/%s", c.App.Version))
+}
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"github.com/go-pkg-org/gopkg/internal/archive"
 	"github.com/go-pkg-org/gopkg/internal/pkgarchiver"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/urfave/cli/v2"
 	"os"
+	"time"
 )
 
 func main() {
@@ -46,8 +48,57 @@ func main() {
 				Name:  "ftp-dir",
 				Usage: "base dir for FTP archive",
 			},
+			&cli.StringFlag{
+				Name:  "storage-dir",
+				Usage: "local archive directory (used instead of FTP)",
+			},
 		},
 		Action: pkgarchiver.Execute,
+		Commands: []*cli.Command{
+			{
+				Name:  "mirror",
+				Usage: "mirror packages from a source archive into the storage",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "source",
+						Usage: "source archive address",
+						Value: archive.DefaultURL,
+					},
+					&cli.StringFlag{
+						Name:     "archive-keyring",
+						Usage:    "path to the source archive keyring (to validate package signatures)",
+						Required: true,
+					},
+					&cli.StringSliceFlag{
+						Name:  "prefix",
+						Usage: "only mirror packages whose alias starts with prefix",
+					},
+					&cli.StringFlag{
+						Name:  "os",
+						Usage: "only mirror binary packages for given OS",
+					},
+					&cli.StringFlag{
+						Name:  "arch",
+						Usage: "only mirror binary packages for given arch",
+					},
+					&cli.BoolFlag{
+						Name:  "latest-only",
+						Usage: "only mirror the latest release of each package",
+					},
+					&cli.DurationFlag{
+						Name:  "timeout",
+						Usage: "timeout for connecting to the source archive",
+						Value: 30 * time.Second,
+					},
+					&cli.IntFlag{
+						Name:  "retries",
+						Usage: "number of retries on transient network errors",
+						Value: 3,
+					},
+				},
+				Action: pkgarchiver.Mirror,
+			},
+		},
 	}
 
 	if err := app.Run(os.Args); err != nil {
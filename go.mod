@@ -6,8 +6,9 @@ require (
 	github.com/golang/mock v1.4.4
 	github.com/jlaffaye/ftp v0.0.0-20201021201046-0de5c29d4555
 	github.com/kelseyhightower/envconfig v1.4.0
+	github.com/klauspost/compress v1.11.3
 	github.com/rs/zerolog v1.20.0
 	github.com/urfave/cli/v2 v2.2.0
 	golang.org/x/crypto v0.0.0-20190308221718-c2843e01d9a2
-	gopkg.in/yaml.v2 v2.3.0
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -1,12 +1,64 @@
 package archive
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+)
+
+// IndexFormatVersion is the version of the index produced by this version of gopkg
+const IndexFormatVersion = "1.0"
+
+// legacyIndexFormatVersion is the version of the indexes written before the format was versioned
+const legacyIndexFormatVersion = "0.0"
+
 // Index represent an Archive index
 // the index is used to perform packages lookup
 type Index struct {
+	// FormatVersion is the index format version (empty for legacy indexes)
+	FormatVersion string `json:",omitempty"`
 	// Packages is the list of existing packages on the archive
 	Packages map[string]Package
 }
 
+// NewIndex returns a brand new empty index
+func NewIndex() Index {
+	return Index{FormatVersion: IndexFormatVersion, Packages: map[string]Package{}}
+}
+
+// DecodeIndex reads an index from r
+// indexes produced by a newer (incompatible) gopkg are rejected
+func DecodeIndex(r io.Reader) (Index, error) {
+	var index Index
+	if err := json.NewDecoder(r).Decode(&index); err != nil {
+		return Index{}, err
+	}
+
+	if index.FormatVersion == "" {
+		index.FormatVersion = legacyIndexFormatVersion
+	}
+	if err := pkg.CheckFormatVersion(index.FormatVersion, IndexFormatVersion); err != nil {
+		return Index{}, fmt.Errorf("index: %w", err)
+	}
+
+	// legacy indexes are compatible with the current format, only make sure they are usable
+	if index.Packages == nil {
+		index.Packages = map[string]Package{}
+	}
+
+	return index, nil
+}
+
+// EncodeIndex writes the index to w using the current format version
+func EncodeIndex(w io.Writer, index Index) error {
+	index.FormatVersion = IndexFormatVersion
+	return json.NewEncoder(w).Encode(index)
+}
+
 // Package represent an installable package
 type Package struct {
 	// Description contains the package description
@@ -22,4 +74,17 @@ type Release struct {
 	OS   string
 	Arch string
 	Path string
+	// Checksum is the hex encoded SHA-256 of the package file
+	// it may be empty for packages uploaded before checksums were recorded
+	Checksum string `json:",omitempty"`
+}
+
+// Checksum returns the checksum of given package content as stored in the index
+func Checksum(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
@@ -0,0 +1,42 @@
+package archive
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+)
+
+func TestDecodeIndex(t *testing.T) {
+	// legacy index without version
+	index, err := DecodeIndex(strings.NewReader(`{"Packages":{"foo/bar":{"LatestRelease":"1.0.0-1"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.FormatVersion != legacyIndexFormatVersion || index.Packages["foo/bar"].LatestRelease != "1.0.0-1" {
+		t.Errorf("wrong legacy index: %+v", index)
+	}
+
+	// index produced by a newer gopkg
+	_, err = DecodeIndex(strings.NewReader(`{"FormatVersion":"2.0","Packages":{}}`))
+	if !errors.Is(err, pkg.ErrUnsupportedFormat) {
+		t.Errorf("DecodeIndex should have returned ErrUnsupportedFormat (got %v)", err)
+	}
+}
+
+func TestEncodeIndex(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeIndex(&buf, Index{FormatVersion: legacyIndexFormatVersion}); err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := DecodeIndex(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.FormatVersion != IndexFormatVersion {
+		t.Errorf("index has not been upgraded (got %s)", index.FormatVersion)
+	}
+}
@@ -1,10 +1,14 @@
 package archive
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/go-pkg-org/gopkg/internal/pkg"
+	"io"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
 //go:generate mockgen -destination=../archive_mock/client_mock.go -package=archive_mock . Client
@@ -20,26 +24,31 @@ type Client interface {
 	GetReleases(pkgName string) (map[string][]Release, error)
 	// GetLatestRelease get the latest available release of given package
 	GetLatestRelease(alias, os, arch string) (pkg.File, error)
+	// GetRelease get the given release of given package
+	GetRelease(alias, version, os, arch string) (pkg.File, error)
+	// Open opens given archive file for reading (path is relative to the archive root)
+	Open(path string) (io.ReadCloser, error)
 }
 
 type client struct {
-	url   string
+	url         string
+	httpClient  *http.Client
+	downloadDir string
+	// dir is set when the archive is a local directory
+	dir   string
 	index Index
 }
 
 func (c *client) GetIndex() (Index, error) {
-	url := fmt.Sprintf("%s/index.json", c.url)
-	resp, err := http.Get(url)
+	r, err := c.open("index.json")
 	if err != nil {
-		return Index{}, err
-	}
-	if resp.StatusCode != 200 {
-		return Index{}, fmt.Errorf("error while getting index: %s", resp.Status)
+		return Index{}, fmt.Errorf("error while getting index: %w", err)
 	}
+	defer r.Close()
 
-	var index Index
-	if err := json.NewDecoder(resp.Body).Decode(&index); err != nil {
-		return Index{}, fmt.Errorf("error while getting index: %s", err)
+	index, err := DecodeIndex(r)
+	if err != nil {
+		return Index{}, fmt.Errorf("error while getting index: %w", err)
 	}
 
 	c.index = index
@@ -56,7 +65,7 @@ func (c *client) GetReleases(pkgName string) (map[string][]Release, error) {
 
 	p, exist := c.index.Packages[pkgName]
 	if !exist {
-		return nil, fmt.Errorf("package %s doesn't exist", pkgName)
+		return nil, fmt.Errorf("%w: %s", pkg.ErrNotFound, pkgName)
 	}
 
 	return p.Releases, nil
@@ -72,38 +81,147 @@ func (c *client) GetLatestRelease(alias, os, arch string) (pkg.File, error) {
 
 	p, exist := c.index.Packages[alias]
 	if !exist {
-		return nil, fmt.Errorf("package %s doesn't exist", alias)
+		return nil, fmt.Errorf("%w: %s", pkg.ErrNotFound, alias)
+	}
+
+	if _, exist := p.Releases[p.LatestRelease]; !exist {
+		return nil, fmt.Errorf("%w: no release of package %s for %s/%s", pkg.ErrWrongTarget, alias, os, arch)
+	}
+
+	return c.GetRelease(alias, p.LatestRelease, os, arch)
+}
+
+func (c *client) GetRelease(alias, version, os, arch string) (pkg.File, error) {
+	// Refresh index if needed
+	if len(c.index.Packages) == 0 {
+		if _, err := c.GetIndex(); err != nil {
+			return nil, err
+		}
+	}
+
+	p, exist := c.index.Packages[alias]
+	if !exist {
+		return nil, fmt.Errorf("%w: %s", pkg.ErrNotFound, alias)
 	}
 
-	releases := p.Releases[p.LatestRelease]
+	releases, exist := p.Releases[version]
+	if !exist {
+		return nil, fmt.Errorf("%w: %s@%s", pkg.ErrNotFound, alias, version)
+	}
 
-	var pkgURL string
+	var pkgRelease Release
 	// Only one release in case of source package
 	if len(releases) == 1 {
-		pkgURL = fmt.Sprintf("%s/%s", c.url, releases[0].Path)
+		pkgRelease = releases[0]
 	} else {
-		for _, release := range p.Releases[p.LatestRelease] {
+		for _, release := range releases {
 			if release.OS == os && release.Arch == arch {
-				pkgURL = fmt.Sprintf("%s/%s", c.url, release.Path)
+				pkgRelease = release
 				break
 			}
 		}
 	}
 
-	resp, err := http.Get(pkgURL)
+	if pkgRelease.Path == "" {
+		return nil, fmt.Errorf("%w: no release of package %s for %s/%s", pkg.ErrWrongTarget, alias, os, arch)
+	}
+
+	path, err := c.download(pkgRelease)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("error while getting release %s: %w", version, err)
+	}
+
+	return pkg.OpenFile(path)
+}
+
+func (c *client) Open(path string) (io.ReadCloser, error) {
+	r, err := c.open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error while getting file: %w", err)
+	}
+
+	return r, nil
+}
+
+// open returns a reader for the given archive file, relative to the archive root
+func (c *client) open(path string) (io.ReadCloser, error) {
+	if c.dir != "" {
+		f, err := os.Open(filepath.Join(c.dir, filepath.FromSlash(path)))
+		if err != nil {
+			return nil, notFound(err, path)
+		}
+		return f, nil
+	}
+
+	url := fmt.Sprintf("%s/%s", c.url, path)
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		return nil, &NetworkError{URL: url, Err: err}
 	}
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("error while getting last release: %s", resp.Status)
+	if err := checkStatus(resp, path); err != nil {
+		resp.Body.Close()
+		return nil, err
 	}
 
-	return pkg.Read(resp.Body)
+	return resp.Body, nil
 }
 
 // NewClient create a new client for an Archive
-func NewClient(url string) (Client, error) {
+// addr may be an HTTP(S) URL, a file:// URL or a local directory
+// laid out like the archive storage (index.json, <alias>/<file>.pkg)
+// packages are downloaded into downloadDir (a temporary directory if empty)
+func NewClient(addr string, httpClient *http.Client, downloadDir string) (Client, error) {
+	dir, err := getLocalDir(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if dir != "" {
+		fi, err := os.Stat(dir)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			return nil, fmt.Errorf("archive %s is not a directory", dir)
+		}
+	}
+
+	if downloadDir == "" {
+		downloadDir = filepath.Join(os.TempDir(), "gopkg-downloads")
+	}
+
 	return &client{
-		url: url,
+		url:         strings.TrimSuffix(addr, "/"),
+		httpClient:  httpClient,
+		downloadDir: downloadDir,
+		dir:         dir,
 	}, nil
 }
+
+// getLocalDir returns the directory targeted by addr
+// or an empty string if addr is a remote archive
+func getLocalDir(addr string) (string, error) {
+	// Windows path such as C:\archive would be parsed as an URL with scheme c
+	if filepath.VolumeName(addr) != "" {
+		return addr, nil
+	}
+
+	u, err := url.Parse(addr)
+	if err != nil {
+		return "", err
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		return "", nil
+	case "file":
+		if u.Host != "" && u.Host != "localhost" {
+			return "", fmt.Errorf("non local file archive: %s", addr)
+		}
+		return filepath.FromSlash(u.Path), nil
+	case "":
+		return addr, nil
+	default:
+		return "", fmt.Errorf("non managed archive scheme: %s", u.Scheme)
+	}
+}
@@ -0,0 +1,304 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewClient(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gopkg_*")
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	c, err := NewClient("https://archive.gopkg.org/", http.DefaultClient, "")
+	if err != nil {
+		t.Error(err)
+	}
+	if c.(*client).dir != "" || c.(*client).url != "https://archive.gopkg.org" {
+		t.Errorf("wrong remote client (%+v)", c)
+	}
+
+	c, err = NewClient("file://"+filepath.ToSlash(dir), http.DefaultClient, "")
+	if err != nil {
+		t.Error(err)
+	}
+	if c.(*client).dir != dir {
+		t.Errorf("wrong local client dir (got %s want %s)", c.(*client).dir, dir)
+	}
+
+	c, err = NewClient(dir, http.DefaultClient, "")
+	if err != nil {
+		t.Error(err)
+	}
+	if c.(*client).dir != dir {
+		t.Errorf("wrong local client dir (got %s want %s)", c.(*client).dir, dir)
+	}
+
+	if _, err := NewClient(filepath.Join(dir, "missing"), http.DefaultClient, ""); err == nil {
+		t.Error("NewClient should have failed with missing directory")
+	}
+
+	if _, err := NewClient("ftp://archive.gopkg.org", http.DefaultClient, ""); err == nil {
+		t.Error("NewClient should have failed with unknown scheme")
+	}
+}
+
+func TestClient_Local(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gopkg_*")
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	// Create a binary package
+	if err := ioutil.WriteFile(filepath.Join(dir, "package.yaml"),
+		[]byte("alias: foo/bar\ntarget_os: linux\ntarget_arch: amd64\nrelease_version: 1.0.0-1\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "foo", "bar"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := pkg.Write(filepath.Join(dir, "foo", "bar", "foo-bar_1.0.0-1_linux_amd64.pkg"), []pkg.Entry{
+		{FilePath: filepath.Join(dir, "package.yaml"), ArchivePath: "package.yaml"},
+	}, true, pkg.NoCompression, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Create the index
+	b, _ := json.Marshal(Index{Packages: map[string]Package{
+		"foo/bar": {
+			Releases: map[string][]Release{
+				"1.0.0-1": {
+					{OS: "linux", Arch: "amd64", Path: "foo/bar/foo-bar_1.0.0-1_linux_amd64.pkg"},
+					{OS: "darwin", Arch: "amd64", Path: "foo/bar/foo-bar_1.0.0-1_darwin_amd64.pkg"},
+				},
+			},
+			LatestRelease: "1.0.0-1",
+		},
+	}})
+	if err := ioutil.WriteFile(filepath.Join(dir, "index.json"), b, 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewClient("file://"+filepath.ToSlash(dir), http.DefaultClient, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := c.GetIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Packages) != 1 {
+		t.Errorf("wrong number of packages: %d", len(index.Packages))
+	}
+
+	p, err := c.GetLatestRelease("foo/bar", "linux", "amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := p.Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Alias != "foo/bar" || m.ReleaseVersion != "1.0.0-1" {
+		t.Errorf("wrong package metadata (%+v)", m)
+	}
+
+	// darwin release is referenced but missing
+	if _, err := c.GetLatestRelease("foo/bar", "darwin", "amd64"); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("GetLatestRelease should have failed with ErrFileNotFound (got %v)", err)
+	}
+	if _, err := c.Open("foo/bar/missing.pkg"); !errors.Is(err, pkg.ErrNotFound) {
+		t.Errorf("Open should have failed with ErrNotFound (got %v)", err)
+	}
+
+	if _, err := c.GetLatestRelease("foo/bar", "windows", "amd64"); err == nil {
+		t.Error("GetLatestRelease should have failed with missing release")
+	}
+
+	if _, err := c.GetRelease("foo/bar", "1.0.0-1", "linux", "amd64"); err != nil {
+		t.Errorf("GetRelease has failed: %s", err)
+	}
+	if _, err := c.GetRelease("foo/bar", "0.9.0-1", "linux", "amd64"); !errors.Is(err, pkg.ErrNotFound) {
+		t.Errorf("GetRelease should have failed with ErrNotFound (got %v)", err)
+	}
+}
+
+func TestClient_Remote(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gopkg_*")
+	downloadDir, _ := ioutil.TempDir("", "gopkg_*")
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+		os.RemoveAll(downloadDir)
+	})
+
+	// Create a source package
+	if err := ioutil.WriteFile(filepath.Join(dir, "package.yaml"),
+		[]byte("alias: foo/bar\nrelease_version: 1.0.0-1\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	pkgPath := filepath.Join(dir, "foo-bar-src_1.0.0-1.pkg")
+	if err := pkg.Write(pkgPath, []pkg.Entry{
+		{FilePath: filepath.Join(dir, "package.yaml"), ArchivePath: "package.yaml"},
+	}, true, pkg.NoCompression, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	pkgBytes, _ := ioutil.ReadFile(pkgPath)
+
+	// Simulate an interrupted download
+	if err := ioutil.WriteFile(filepath.Join(downloadDir, "foo-bar-src_1.0.0-1.pkg.part"), pkgBytes[:100], 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.FileServer(http.Dir(dir)).ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, srv.Client(), downloadDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.(*client).index = Index{Packages: map[string]Package{
+		"foo/bar": {
+			Releases: map[string][]Release{
+				"1.0.0-1": {{Path: "foo-bar-src_1.0.0-1.pkg", Checksum: checksum(pkgBytes)}},
+			},
+			LatestRelease: "1.0.0-1",
+		},
+	}}
+
+	p, err := c.GetLatestRelease("foo/bar", "linux", "amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, _ := p.Metadata(); m.Alias != "foo/bar" {
+		t.Errorf("wrong package metadata (%+v)", m)
+	}
+	if len(ranges) != 1 || ranges[0] != "bytes=100-" {
+		t.Errorf("download should have been resumed (%v)", ranges)
+	}
+
+	// Package is now cached
+	if _, err := c.GetLatestRelease("foo/bar", "linux", "amd64"); err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) != 1 {
+		t.Errorf("cached package should not have been downloaded (%v)", ranges)
+	}
+
+	// Corrupted package
+	c.(*client).index.Packages["foo/bar"].Releases["1.0.0-1"][0].Checksum = checksum([]byte("foo"))
+	if _, err := c.GetLatestRelease("foo/bar", "linux", "amd64"); !errors.Is(err, pkg.ErrCorruptPackage) {
+		t.Errorf("GetLatestRelease should have failed with ErrCorruptPackage (got %v)", err)
+	}
+	if _, err := os.Stat(filepath.Join(downloadDir, "foo-bar-src_1.0.0-1.pkg.part")); !os.IsNotExist(err) {
+		t.Error("corrupted download should have been removed")
+	}
+}
+
+func TestClient_Download(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gopkg_*")
+	downloadDir, _ := ioutil.TempDir("", "gopkg_*")
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+		os.RemoveAll(downloadDir)
+	})
+
+	// Two packages sharing the same file name
+	for _, alias := range []string{"foo", "bar"} {
+		if err := os.MkdirAll(filepath.Join(dir, alias), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, alias, "foo-src_1.0.0-1.pkg"), []byte(alias+" package"), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.FileServer(http.Dir(dir)).ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	c := &client{url: srv.URL, httpClient: srv.Client(), downloadDir: downloadDir}
+
+	// Stale partial download of a release without checksum, bigger than the package
+	partPath := filepath.Join(downloadDir, "foo", "foo-src_1.0.0-1.pkg.part")
+	os.MkdirAll(filepath.Dir(partPath), 0750)
+	if err := ioutil.WriteFile(partPath, []byte("stale partial download"), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := c.download(Release{Path: "foo/foo-src_1.0.0-1.pkg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadFile(path); string(b) != "foo package" {
+		t.Errorf("wrong downloaded content: %s", b)
+	}
+	if len(ranges) != 1 || ranges[0] != "" {
+		t.Errorf("partial download should have been discarded (%v)", ranges)
+	}
+
+	// Partial download bigger than the package: the server replies 416
+	ranges = nil
+	partPath = filepath.Join(downloadDir, "bar", "foo-src_1.0.0-1.pkg.part")
+	os.MkdirAll(filepath.Dir(partPath), 0750)
+	if err := ioutil.WriteFile(partPath, []byte("stale partial download"), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err = c.download(Release{Path: "bar/foo-src_1.0.0-1.pkg", Checksum: checksum([]byte("bar package"))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadFile(path); string(b) != "bar package" {
+		t.Errorf("wrong downloaded content: %s", b)
+	}
+	if len(ranges) != 2 || ranges[1] != "" {
+		t.Errorf("download should have been restarted (%v)", ranges)
+	}
+
+	// Hostile index
+	if _, err := c.download(Release{Path: "../evil.pkg"}); !errors.Is(err, pkg.ErrUnsafePath) {
+		t.Errorf("download should have failed with ErrUnsafePath (got %v)", err)
+	}
+
+	// Missing file
+	if _, err := c.download(Release{Path: "foo/missing.pkg"}); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("download should have failed with ErrFileNotFound (got %v)", err)
+	}
+}
+
+func TestClient_NotFound(t *testing.T) {
+	c := &client{index: Index{Packages: map[string]Package{"foo/bar": {}}}}
+
+	if _, err := c.GetReleases("bar/foo"); !errors.Is(err, pkg.ErrNotFound) {
+		t.Errorf("GetReleases should have failed with ErrNotFound (got %v)", err)
+	}
+	if _, err := c.GetLatestRelease("bar/foo", "linux", "amd64"); !errors.Is(err, pkg.ErrNotFound) {
+		t.Errorf("GetLatestRelease should have failed with ErrNotFound (got %v)", err)
+	}
+	if _, err := c.GetLatestRelease("foo/bar", "linux", "amd64"); !errors.Is(err, pkg.ErrWrongTarget) {
+		t.Errorf("GetLatestRelease should have failed with ErrWrongTarget (got %v)", err)
+	}
+}
+
+func checksum(b []byte) string {
+	sum, _ := Checksum(bytes.NewReader(b))
+	return sum
+}
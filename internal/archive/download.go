@@ -0,0 +1,194 @@
+package archive
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+	"github.com/go-pkg-org/gopkg/internal/util/progress"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrChecksumMismatch is returned when a package doesn't match the index checksum
+var ErrChecksumMismatch = fmt.Errorf("%w: checksum mismatch", pkg.ErrCorruptPackage)
+
+// download fetch the given release into the download directory and returns the file path
+// interrupted downloads are resumed and the checksum is verified (if known)
+// downloads are stored under their archive path, the releases without checksum are always downloaded again
+func (c *client) download(release Release) (string, error) {
+	// the path comes from the index
+	if err := pkg.ValidatePath(release.Path); err != nil {
+		return "", fmt.Errorf("invalid release path: %w", err)
+	}
+
+	// Local archive: no need to copy the file
+	if c.dir != "" {
+		path := filepath.Join(c.dir, filepath.FromSlash(release.Path))
+		if err := verifyChecksum(path, release.Checksum); err != nil {
+			return "", notFound(err, release.Path)
+		}
+		return path, nil
+	}
+
+	target := filepath.Join(c.downloadDir, filepath.FromSlash(release.Path))
+	if err := os.MkdirAll(filepath.Dir(target), 0750); err != nil {
+		return "", err
+	}
+
+	// Already downloaded
+	if release.Checksum != "" {
+		if err := verifyChecksum(target, release.Checksum); err == nil {
+			log.Debug().Str("path", target).Msg("Using cached package")
+			return target, nil
+		}
+	}
+
+	partPath := target + ".part"
+
+	// A partial download cannot be verified: start from scratch
+	if release.Checksum == "" {
+		if err := os.Remove(partPath); err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+
+	if err := c.fetch(release.Path, partPath); err != nil {
+		return "", err
+	}
+
+	if err := verifyChecksum(partPath, release.Checksum); err != nil {
+		// Do not try to resume a corrupted download
+		os.Remove(partPath)
+		return "", err
+	}
+
+	if err := os.Rename(partPath, target); err != nil {
+		return "", err
+	}
+
+	return target, nil
+}
+
+// fetch download the given archive file into target, resuming any partial download
+func (c *client) fetch(path, target string) error {
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/%s", c.url, path)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return &NetworkError{URL: url, Err: err}
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		log.Debug().Str("path", path).Int64("offset", offset).Msg("Resuming download")
+	case http.StatusOK:
+		// Range not supported: restart from scratch
+		if offset > 0 {
+			if err := f.Truncate(0); err != nil {
+				return err
+			}
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			offset = 0
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// File is already complete
+		if size, ok := rangeSize(resp); ok && size == offset {
+			return nil
+		}
+		if offset == 0 {
+			return checkStatus(resp, path)
+		}
+
+		// Stale partial download: restart from scratch
+		log.Debug().Str("path", path).Int64("offset", offset).Msg("Discarding partial download")
+		f.Close()
+		if err := os.Remove(target); err != nil {
+			return err
+		}
+		return c.fetch(path, target)
+	default:
+		return checkStatus(resp, path)
+	}
+
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
+
+	bar := progress.New(filepath.Base(path), offset, total)
+	defer bar.Finish()
+
+	if _, err := io.Copy(io.MultiWriter(f, bar), resp.Body); err != nil {
+		return &NetworkError{URL: url, Err: err}
+	}
+
+	return nil
+}
+
+// rangeSize returns the complete file size from the Content-Range response header
+func rangeSize(resp *http.Response) (int64, bool) {
+	contentRange := resp.Header.Get("Content-Range")
+
+	i := strings.LastIndex(contentRange, "/")
+	if !strings.HasPrefix(contentRange, "bytes ") || i == -1 {
+		return 0, false
+	}
+
+	size, err := strconv.ParseInt(contentRange[i+1:], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return size, true
+}
+
+// verifyChecksum make sure the file at path match given checksum
+// an empty checksum is not verified
+func verifyChecksum(path, checksum string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if checksum == "" {
+		return nil
+	}
+
+	sum, err := Checksum(f)
+	if err != nil {
+		return err
+	}
+
+	if sum != checksum {
+		return ErrChecksumMismatch
+	}
+
+	return nil
+}
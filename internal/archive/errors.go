@@ -0,0 +1,51 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+)
+
+// ErrFileNotFound is returned when a file is missing from the archive
+var ErrFileNotFound = fmt.Errorf("%w: missing archive file", pkg.ErrNotFound)
+
+// NetworkError is returned when the archive cannot be reached
+// or fails to answer a request
+type NetworkError struct {
+	URL string
+	Err error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("error while requesting %s: %s", e.URL, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+// checkStatus returns an error matching the response status if not successful
+// missing files are reported as ErrFileNotFound, like for local archives
+func checkStatus(resp *http.Response, path string) error {
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%s: %w", path, ErrFileNotFound)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return &NetworkError{URL: resp.Request.URL.String(), Err: errors.New(resp.Status)}
+	default:
+		return nil
+	}
+}
+
+// notFound converts the missing local files errors into ErrFileNotFound
+func notFound(err error, path string) error {
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%s: %w", path, ErrFileNotFound)
+	}
+
+	return err
+}
@@ -3,19 +3,22 @@ package build
 import (
 	"fmt"
 	"github.com/go-pkg-org/gopkg/internal/config"
+	"github.com/go-pkg-org/gopkg/internal/patch"
 	"github.com/go-pkg-org/gopkg/internal/pkg"
+	"github.com/go-pkg-org/gopkg/internal/util"
 	"github.com/rs/zerolog/log"
-	"gopkg.in/yaml.v2"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // Build will build control package located as directory
 // and produce binary / dev packages into directory/build folder
-func Build(path string) error {
+// packages are compressed using given compression
+func Build(path string, compression pkg.Compression) error {
 	// If path is pointing to a .pkg file, extract it
 	if strings.HasSuffix(path, "."+pkg.FileExt) {
 		log.Debug().Str("package", path).Msg("Extracting control package")
@@ -57,11 +60,31 @@ func Build(path string) error {
 	}
 	releaseVersion := latestRelease.Version
 
+	// Timestamp used for all packages entries, for reproducibility
+	modTime, err := latestRelease.SourceDate()
+	if err != nil {
+		return err
+	}
+
 	log.Info().
 		Str("importPath", m.ImportPath).
 		Str("version", releaseVersion).
 		Msgf("Building for control package")
 
+	// The control package holds the pristine sources: its patches are applied at build time
+	if err := patch.Revert(path); err != nil {
+		return err
+	}
+	if err := buildControlPackage(path, m.ImportPath, releaseVersion, compression, modTime); err != nil {
+		return err
+	}
+
+	// Apply the downstream patches before testing & packaging the sources
+	patches, err := patch.Apply(path)
+	if err != nil {
+		return err
+	}
+
 	// Run unit tests
 	cmd := exec.Command("go", "test", "./...")
 	cmd.Env = append(os.Environ(), fmt.Sprintf("GOPATH=%s", goPath))
@@ -79,22 +102,23 @@ func Build(path string) error {
 	}
 
 	// Build source package
-	if err := buildSourcePackage(path, m.ImportPath, releaseVersion); err != nil {
+	if err := buildSourcePackage(path, m.ImportPath, releaseVersion, patches, compression, modTime); err != nil {
 		return err
 	}
 
+	vars := pkg.NewBuildVars(releaseVersion, getCommit(path))
+
 	for _, p := range m.Packages {
 		for targetOs, targetArches := range p.Targets {
 			for _, targetArch := range targetArches {
-				if err = buildBinaryPackage(goPath, path, releaseVersion, targetOs, targetArch, p); err != nil {
+				if err = buildBinaryPackage(goPath, path, targetOs, targetArch, p, vars, compression, modTime); err != nil {
 					return err
 				}
 			}
 		}
 	}
 
-	// Finally build control package
-	return buildControlPackage(path, m.ImportPath, releaseVersion)
+	return nil
 }
 
 func extractControlPackage(path string) (string, error) {
@@ -109,43 +133,39 @@ func extractControlPackage(path string) (string, error) {
 		return "", fmt.Errorf("%s is not a control package", fileName)
 	}
 
-	f, err := pkg.ReadFile(path)
+	f, err := pkg.OpenFile(path)
 	if err != nil {
 		return "", err
 	}
 
 	baseDir := filepath.Dir(path)
-	for p, b := range f.Files() {
-		targetPath := filepath.Join(baseDir, p)
-		log.Debug().Str("path", targetPath).Msg("Writing file")
-
-		// Create directory if needed
-		if err := os.MkdirAll(filepath.Dir(targetPath), 0750); err != nil {
-			return "", err
-		}
-
-		// Then creating file
-		if err := ioutil.WriteFile(targetPath, b, 0640); err != nil {
-			return "", err
-		}
+	if _, err := pkg.Extract(f, baseDir, func(h *pkg.Header) bool {
+		return !pkg.IsMetaFile(h.Name)
+	}); err != nil {
+		return "", err
 	}
 
 	return strings.TrimSuffix(path, "."+pkg.FileExt), nil
 }
 
-func buildControlPackage(directory, importPath string, releaseVersion string) error {
+func buildControlPackage(directory, importPath string, releaseVersion string, compression pkg.Compression,
+	modTime time.Time) error {
 	fileName, err := pkg.GetFileName(importPath, releaseVersion, "", "", pkg.Control)
 	if err != nil {
 		return err
 	}
 
-	dir, err := pkg.CreateEntries(directory, strings.TrimSuffix(fileName, "."+pkg.FileExt), []string{".git"})
+	prefix := strings.TrimSuffix(fileName, "."+pkg.FileExt)
+	dir, err := pkg.CreateEntries(directory, prefix, []string{".git"})
 	if err != nil {
 		return err
 	}
+	// the files generated by the builds are not part of the control package
+	dir = excludeEntries(dir, filepath.Join(prefix, "build"), filepath.Join(prefix, "package.yaml"),
+		filepath.Join(prefix, pkg.GoPkgDir, pkg.PatchesDir, pkg.AppliedFile))
 
 	// Save the package in `./<fileName>`
-	if err := pkg.Write(fileName, dir, true); err != nil {
+	if err := pkg.Write(fileName, dir, true, compression, modTime); err != nil {
 		return err
 	}
 
@@ -153,7 +173,8 @@ func buildControlPackage(directory, importPath string, releaseVersion string) er
 	return nil
 }
 
-func buildSourcePackage(directory, importPath, releaseVersion string) error {
+func buildSourcePackage(directory, importPath, releaseVersion string, patches []string, compression pkg.Compression,
+	modTime time.Time) error {
 	fileName, err := pkg.GetFileName(importPath, releaseVersion, "", "", pkg.Source)
 	if err != nil {
 		return err
@@ -163,27 +184,37 @@ func buildSourcePackage(directory, importPath, releaseVersion string) error {
 	if err != nil {
 		return err
 	}
+	// package.yaml was generated in the sources by previous gopkg versions
+	dir = excludeEntries(dir, filepath.Join(importPath, "build"), filepath.Join(importPath, "package.yaml"))
 
 	// Create package definition
 	p := pkg.Meta{
 		Alias:          importPath,
 		ReleaseVersion: releaseVersion,
+		Patches:        patches,
 	}
-	b, err := yaml.Marshal(p)
+	b, err := util.EncodeYAML(p)
 	if err != nil {
 		return err
 	}
-	// TODO improve that
-	if err := ioutil.WriteFile(filepath.Join(directory, "package.yaml"), b, 0640); err != nil {
+	buildDir := filepath.Join(directory, "build", strings.TrimSuffix(fileName, "."+pkg.FileExt))
+	if err := os.MkdirAll(buildDir, 0750); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(buildDir, "package.yaml"), b, 0640); err != nil {
 		return err
 	}
 	dir = append(dir, pkg.Entry{
-		FilePath:    filepath.Join(directory, "package.yaml"),
+		FilePath:    filepath.Join(buildDir, "package.yaml"),
 		ArchivePath: "package.yaml",
 	})
 
 	// Save the package in `./<fileName>`
-	if err := pkg.Write(fileName, dir, true); err != nil {
+	if err := pkg.Write(fileName, dir, true, compression, modTime); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(buildDir); err != nil {
 		return err
 	}
 
@@ -191,32 +222,51 @@ func buildSourcePackage(directory, importPath, releaseVersion string) error {
 	return nil
 }
 
-func buildBinaryPackage(goPath, directory, releaseVersion, targetOs, targetArch string, p pkg.Meta) error {
-	pkgName, err := pkg.GetFileName(p.Alias, releaseVersion, targetOs, targetArch, pkg.Binary)
+func buildBinaryPackage(goPath, directory, targetOs, targetArch string, p pkg.Meta, vars pkg.BuildVars,
+	compression pkg.Compression, modTime time.Time) error {
+	pkgName, err := pkg.GetFileName(p.Alias, vars.Version, targetOs, targetArch, pkg.Binary)
 	if err != nil {
 		return err
 	}
 
 	buildDir := filepath.Join(directory, "build", pkgName)
 
-	cmd := exec.Command("go", "build", "-o", filepath.Join(buildDir, p.BinName), p.Main)
+	vars.OS = targetOs
+	vars.Arch = targetArch
+	args, err := getBuildArgs(p, vars, filepath.Join(buildDir, p.BinName))
+	if err != nil {
+		return err
+	}
+	env, err := getBuildEnv(p, vars)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command("go", args...)
 	log.Trace().Msgf("Executing `%s`", cmd.String())
 	cmd.Dir = directory
 	cmd.Stdout = ioutil.Discard
 	cmd.Stderr = os.Stderr
 	cmd.Env = append(os.Environ(), fmt.Sprintf("GOOS=%s", targetOs),
 		fmt.Sprintf("GOARCH=%s", targetArch), fmt.Sprintf("GOPATH=%s", goPath))
+	cmd.Env = append(cmd.Env, env...)
 	if err := cmd.Run(); err != nil {
 		return err
 	}
 
 	// Copy package definition
 	// this is used later on to determinate which package we are installing & populate info
+	// the build settings are only needed to build the package
 	p.Targets = nil
+	p.Tags = nil
+	p.LdFlags = ""
+	p.TrimPath = nil
+	p.CGOEnabled = nil
+	p.Env = nil
 	p.TargetOS = targetOs
 	p.TargetArch = targetArch
-	p.ReleaseVersion = releaseVersion
-	b, err := yaml.Marshal(p)
+	p.ReleaseVersion = vars.Version
+	b, err := util.EncodeYAML(p)
 	if err != nil {
 		return err
 	}
@@ -237,7 +287,7 @@ func buildBinaryPackage(goPath, directory, releaseVersion, targetOs, targetArch
 			FilePath:    filepath.Join(buildDir, "package.yaml"),
 			ArchivePath: "package.yaml",
 		},
-	}, true)
+	}, true, compression, modTime)
 
 	if err != nil {
 		return err
@@ -251,3 +301,85 @@ func buildBinaryPackage(goPath, directory, releaseVersion, targetOs, targetArch
 	log.Info().Str("package", pkgName).Msg("Successfully built binary package")
 	return nil
 }
+
+// excludeEntries returns the entries not located at (or under) one of the excluded archive paths
+func excludeEntries(entries []pkg.Entry, excluded ...string) []pkg.Entry {
+	var result []pkg.Entry
+	for _, entry := range entries {
+		keep := true
+		for _, path := range excluded {
+			if entry.ArchivePath == path || strings.HasPrefix(entry.ArchivePath, path+string(filepath.Separator)) {
+				keep = false
+				break
+			}
+		}
+
+		if keep {
+			result = append(result, entry)
+		}
+	}
+
+	return result
+}
+
+// getBuildArgs returns the go build arguments for the given package
+func getBuildArgs(p pkg.Meta, vars pkg.BuildVars, output string) ([]string, error) {
+	args := []string{"build"}
+
+	// Strip file system paths & build id so independent builds produce the same binary
+	if p.TrimPath == nil || *p.TrimPath {
+		args = append(args, "-trimpath")
+	}
+
+	ldFlags, err := vars.Expand(p.LdFlags)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ldflags of %s: %w", p.Alias, err)
+	}
+	args = append(args, "-ldflags="+strings.TrimSpace("-buildid= "+ldFlags))
+
+	if len(p.Tags) > 0 {
+		args = append(args, "-tags="+strings.Join(p.Tags, ","))
+	}
+
+	return append(args, "-o", output, p.Main), nil
+}
+
+// getBuildEnv returns the extra environment variables used to build the given package
+func getBuildEnv(p pkg.Meta, vars pkg.BuildVars) ([]string, error) {
+	var env []string
+	if p.CGOEnabled != nil {
+		cgoEnabled := "0"
+		if *p.CGOEnabled {
+			cgoEnabled = "1"
+		}
+		env = append(env, "CGO_ENABLED="+cgoEnabled)
+	}
+
+	for _, e := range p.Env {
+		value, err := vars.Expand(e)
+		if err != nil {
+			return nil, fmt.Errorf("invalid env of %s: %w", p.Alias, err)
+		}
+		env = append(env, value)
+	}
+
+	return env, nil
+}
+
+// getCommit returns the hash of the commit checked out in directory
+// or an empty string if directory is not a git repository
+func getCommit(directory string) string {
+	// do not pick the commit of a parent repository (f.e extracted control package)
+	if _, err := os.Stat(filepath.Join(directory, ".git")); err != nil {
+		return ""
+	}
+
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = directory
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(out))
+}
@@ -1 +1,76 @@
 package build
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+)
+
+func TestGetBuildArgs(t *testing.T) {
+	vars := pkg.NewBuildVars("1.2.0-1", "abcdef")
+	vars.OS = "linux"
+	vars.Arch = "amd64"
+
+	args, err := getBuildArgs(pkg.Meta{Main: "cmd/foo/main.go"}, vars, "build/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"build", "-trimpath", "-ldflags=-buildid=", "-o", "build/foo", "cmd/foo/main.go"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("wrong args: %v", args)
+	}
+
+	trimPath := false
+	args, err = getBuildArgs(pkg.Meta{
+		Main:     "cmd/foo/main.go",
+		Tags:     []string{"netgo", "osusergo"},
+		LdFlags:  "-s -X main.version={{.UpstreamVersion}} -X main.commit={{.Commit}} -X main.target={{.OS}}/{{.Arch}}",
+		TrimPath: &trimPath,
+	}, vars, "build/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{"build",
+		"-ldflags=-buildid= -s -X main.version=1.2.0 -X main.commit=abcdef -X main.target=linux/amd64",
+		"-tags=netgo,osusergo", "-o", "build/foo", "cmd/foo/main.go"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("wrong args: %v", args)
+	}
+
+	if _, err := getBuildArgs(pkg.Meta{LdFlags: "{{.Unknown}}"}, vars, "build/foo"); err == nil {
+		t.Error("getBuildArgs should have failed")
+	}
+}
+
+func TestGetBuildEnv(t *testing.T) {
+	cgoEnabled := false
+	env, err := getBuildEnv(pkg.Meta{CGOEnabled: &cgoEnabled, Env: []string{"GOARM=7", "VERSION={{.Version}}"}},
+		pkg.NewBuildVars("1.2.0-1", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"CGO_ENABLED=0", "GOARM=7", "VERSION=1.2.0-1"}
+	if !reflect.DeepEqual(env, expected) {
+		t.Errorf("wrong env: %v", env)
+	}
+}
+
+func TestExcludeEntries(t *testing.T) {
+	entries := []pkg.Entry{
+		{FilePath: "foo/main.go", ArchivePath: filepath.Join("foo_1.0.0-1", "main.go")},
+		{FilePath: "foo/build", ArchivePath: filepath.Join("foo_1.0.0-1", "build")},
+		{FilePath: "foo/build/foo", ArchivePath: filepath.Join("foo_1.0.0-1", "build", "foo")},
+		{FilePath: "foo/builder.go", ArchivePath: filepath.Join("foo_1.0.0-1", "builder.go")},
+		{FilePath: "foo/package.yaml", ArchivePath: filepath.Join("foo_1.0.0-1", "package.yaml")},
+		{FilePath: "foo/cmd/build/main.go", ArchivePath: filepath.Join("foo_1.0.0-1", "cmd", "build", "main.go")},
+	}
+
+	result := excludeEntries(entries, filepath.Join("foo_1.0.0-1", "build"), filepath.Join("foo_1.0.0-1", "package.yaml"))
+	expected := []pkg.Entry{entries[0], entries[3], entries[5]}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("wrong entries: %v", result)
+	}
+}
@@ -2,13 +2,11 @@ package cache
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"github.com/go-pkg-org/gopkg/internal/archive"
 	"github.com/go-pkg-org/gopkg/internal/config"
 	"github.com/go-pkg-org/gopkg/internal/pkg"
 	"github.com/rs/zerolog/log"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -16,10 +14,10 @@ import (
 )
 
 // ErrPackageAlreadyInstalled is returns when the package we are trying to install is already installed
-var ErrPackageAlreadyInstalled = errors.New("package is already installed")
+var ErrPackageAlreadyInstalled = pkg.ErrAlreadyInstalled
 
 // ErrWrongTarget is returned when the package we are trying to install is not compatible
-var ErrWrongTarget = errors.New("package is not compatible")
+var ErrWrongTarget = pkg.ErrWrongTarget
 
 // Cache is a local gopkg cache
 type Cache interface {
@@ -30,22 +28,24 @@ type Cache interface {
 }
 
 type cache struct {
-	Packages  map[string][]string `json:"packages"`
+	Packages map[string][]string `json:"packages"`
+	// Checksums are the sha256 of the installed files, taken from the package manifest
+	Checksums map[string]string `json:"checksums,omitempty"`
 	arcClient archive.Client
 	cacheFile string
 	conf      *config.Config
 }
 
 func (c *cache) InstallPkgFile(filePath string) (pkg.Meta, error) {
-	p, err := pkg.ReadFile(filePath)
+	p, err := pkg.OpenFile(filePath)
 	if err != nil {
-		return pkg.Meta{}, nil
+		return pkg.Meta{}, err
 	}
 
 	// Try to install package
 	meta, err := c.installPkg(p)
 	if err != nil {
-		return pkg.Meta{}, fmt.Errorf("error while installing package %s: %s", filePath, err)
+		return pkg.Meta{}, fmt.Errorf("error while installing package %s: %w", filePath, err)
 	}
 
 	return meta, nil
@@ -54,7 +54,7 @@ func (c *cache) InstallPkgFile(filePath string) (pkg.Meta, error) {
 func (c *cache) InstallPkg(aliasName string) (pkg.Meta, error) {
 	p, err := c.arcClient.GetLatestRelease(aliasName, runtime.GOOS, runtime.GOARCH)
 	if err != nil {
-		return pkg.Meta{}, nil
+		return pkg.Meta{}, err
 	}
 
 	return c.installPkg(p)
@@ -72,23 +72,24 @@ func (c *cache) installPkg(pkgFile pkg.File) (pkg.Meta, error) {
 		return pkg.Meta{}, ErrPackageAlreadyInstalled
 	}
 
+	// binary package need to match os / arch, source package can be installed no matter what
+	if !meta.IsSource() && (meta.TargetOS != runtime.GOOS || meta.TargetArch != runtime.GOARCH) {
+		return pkg.Meta{}, ErrWrongTarget
+	}
+
+	manifest, err := pkgFile.Manifest()
+	if err != nil {
+		return pkg.Meta{}, err
+	}
+
 	var files []string
-	// source package can be installed no matter what
 	if meta.IsSource() {
-		files, err = installSourcePkg(pkgFile, c.conf.SrcDir)
-		if err != nil {
-			return pkg.Meta{}, err
-		}
+		files, err = installSourcePkg(pkgFile, c.conf.SrcDir, manifest, c.Checksums)
 	} else {
-		// binary package need to match os / arch
-		if meta.TargetOS != runtime.GOOS || meta.TargetArch != runtime.GOARCH {
-			return pkg.Meta{}, ErrWrongTarget
-		}
-
-		files, err = installBinaryPkg(pkgFile, c.conf.BinDir)
-		if err != nil {
-			return pkg.Meta{}, err
-		}
+		files, err = installBinaryPkg(pkgFile, c.conf.BinDir, manifest, c.Checksums)
+	}
+	if err != nil {
+		return pkg.Meta{}, err
 	}
 
 	// Update local cache
@@ -100,59 +101,43 @@ func (c *cache) installPkg(pkgFile pkg.File) (pkg.Meta, error) {
 	return meta, err
 }
 
-func installSourcePkg(pkgFile pkg.File, sourceInstallDir string) ([]string, error) {
-	var files []string
-	for path, content := range pkgFile.Files() {
-		// Do not install package.yaml or package.yml file
-		if path == "package.yaml" || path == "package.yml" {
-			continue
+func installSourcePkg(pkgFile pkg.File, sourceInstallDir string, manifest pkg.Manifest,
+	checksums map[string]string) ([]string, error) {
+	return pkg.Extract(pkgFile, sourceInstallDir, func(h *pkg.Header) bool {
+		// Do not install package.yaml, package.yml or manifest.yaml file
+		if pkg.IsMetaFile(h.Name) {
+			return false
 		}
 
-		filePath := filepath.Join(sourceInstallDir, path)
-		log.Trace().Str("path", filePath).Msg("Writing file")
+		recordChecksum(checksums, manifest, h.Name, filepath.Join(sourceInstallDir, h.Name))
+		return true
+	})
+}
 
-		// create directory if needed
-		if err := os.MkdirAll(filepath.Dir(filePath), 0750); err != nil {
-			return nil, err
+func installBinaryPkg(pkgFile pkg.File, binaryInstallDir string, manifest pkg.Manifest,
+	checksums map[string]string) ([]string, error) {
+	return pkg.Extract(pkgFile, binaryInstallDir, func(h *pkg.Header) bool {
+		if !strings.HasPrefix(h.Name, "bin/") {
+			return false
 		}
+		name := h.Name
+		h.Name = strings.TrimPrefix(h.Name, "bin/")
 
-		// then create file
-		if err := ioutil.WriteFile(filePath, content, 0640); err != nil {
-			return nil, err
+		// packages built before modes were preserved have non executable binaries
+		if h.Type == pkg.RegularFile && h.Mode&0111 == 0 {
+			h.Mode = 0750
 		}
 
-		files = append(files, filePath)
-	}
-
-	return files, nil
+		recordChecksum(checksums, manifest, name, filepath.Join(binaryInstallDir, h.Name))
+		return true
+	})
 }
 
-func installBinaryPkg(pkgFile pkg.File, binaryInstallDir string) ([]string, error) {
-	var files []string
-	for path, content := range pkgFile.Files() {
-		// Do not install package.yaml or package.yml file
-		if path == "package.yaml" || path == "package.yml" {
-			continue
-		}
-
-		if strings.HasPrefix(path, "bin/") {
-			realPath := filepath.Join(binaryInstallDir, strings.TrimPrefix(path, "bin/"))
-			log.Trace().Str("path", realPath).Msg("Writing file")
-
-			// create directory if needed
-			if err := os.MkdirAll(filepath.Dir(realPath), 0750); err != nil {
-				return nil, err
-			}
-
-			if err := ioutil.WriteFile(realPath, content, 0750); err != nil {
-				return nil, err
-			}
-
-			files = append(files, realPath)
-		}
+// recordChecksum save the manifest checksum of the entry installed at path
+func recordChecksum(checksums map[string]string, manifest pkg.Manifest, name, path string) {
+	if entry, exist := manifest[name]; exist && entry.SHA256 != "" {
+		checksums[path] = entry.SHA256
 	}
-
-	return files, nil
 }
 
 func (c *cache) ListPackages(onlyInstalled bool) ([]string, error) {
@@ -178,7 +163,7 @@ func (c *cache) ListPackages(onlyInstalled bool) ([]string, error) {
 func (c *cache) RemovePkg(alias string) error {
 	files, exist := c.Packages[alias]
 	if !exist {
-		return fmt.Errorf("package %s not installed", alias)
+		return fmt.Errorf("%w: %s is not installed", pkg.ErrNotFound, alias)
 	}
 
 	// remove installed files
@@ -186,6 +171,7 @@ func (c *cache) RemovePkg(alias string) error {
 		if err := os.RemoveAll(file); err != nil {
 			log.Warn().Str("file", file).Str("err", err.Error()).Msg("unable to delete file")
 		}
+		delete(c.Checksums, file)
 	}
 
 	// update cache
@@ -213,7 +199,7 @@ func read(path string) (*cache, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return &cache{Packages: map[string][]string{}}, nil
+			return &cache{Packages: map[string][]string{}, Checksums: map[string]string{}}, nil
 		}
 		return nil, err
 	}
@@ -222,6 +208,11 @@ func read(path string) (*cache, error) {
 		return nil, err
 	}
 
+	// caches written before checksums were recorded
+	if c.Checksums == nil {
+		c.Checksums = map[string]string{}
+	}
+
 	return &c, nil
 }
 
@@ -1,6 +1,8 @@
 package cache
 
 import (
+	"errors"
+	"fmt"
 	"github.com/go-pkg-org/gopkg/internal/archive"
 	"github.com/go-pkg-org/gopkg/internal/archive_mock"
 	"github.com/go-pkg-org/gopkg/internal/config"
@@ -8,7 +10,9 @@ import (
 	"github.com/go-pkg-org/gopkg/internal/pkg_mock"
 	"github.com/golang/mock/gomock"
 	"io/ioutil"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -63,10 +67,17 @@ func TestCache_InstallPkg(t *testing.T) {
 		Main:       "main.go",
 		BinName:    "foo-bar",
 	}, nil)
-	p.EXPECT().Files().Return(map[string][]byte{"hello": []byte("world")})
+	p.EXPECT().Manifest().Return(pkg.Manifest{
+		"bin/foo-bar": {Type: pkg.RegularFile, Size: 5, Mode: 0755, SHA256: "abcd"},
+	}, nil)
+	p.EXPECT().Walk(gomock.Any()).DoAndReturn(func(fn pkg.WalkFunc) error {
+		return fn(pkg.Header{Name: "bin/foo-bar", Size: 5, Mode: 0755, Type: pkg.RegularFile},
+			strings.NewReader("world"))
+	})
 
 	cache := cache{
 		Packages:  map[string][]string{},
+		Checksums: map[string]string{},
 		cacheFile: f.Name(),
 		conf: &config.Config{
 			BinDir: binDir,
@@ -80,6 +91,10 @@ func TestCache_InstallPkg(t *testing.T) {
 	if len(cache.Packages) != 1 {
 		t.Errorf("wrong number of packages: %d", len(cache.Packages))
 	}
+
+	if sum := cache.Checksums[filepath.Join(binDir, "foo-bar")]; sum != "abcd" {
+		t.Errorf("checksum has not been recorded (got %s)", sum)
+	}
 }
 
 func TestCache_ListPackages_Archive(t *testing.T) {
@@ -123,8 +138,8 @@ func TestCache_RemovePkg_NotInstalled(t *testing.T) {
 		Packages: map[string][]string{},
 	}
 
-	if err := cache.RemovePkg("foo/bar"); err == nil {
-		t.Error("should have failed")
+	if err := cache.RemovePkg("foo/bar"); !errors.Is(err, pkg.ErrNotFound) {
+		t.Error("should have failed with ErrNotFound")
 	}
 }
 
@@ -140,3 +155,35 @@ func TestCache_RemovePkg(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestCache_InstallPkgFile_Corrupt(t *testing.T) {
+	f, _ := ioutil.TempFile("", "")
+	f.WriteString("this is not a package")
+	f.Close()
+
+	cache := cache{
+		Packages: map[string][]string{},
+	}
+
+	if _, err := cache.InstallPkgFile(f.Name()); !errors.Is(err, pkg.ErrCorruptPackage) {
+		t.Errorf("InstallPkgFile should have failed with ErrCorruptPackage (got %v)", err)
+	}
+}
+
+func TestCache_InstallPkg_NotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	arc := archive_mock.NewMockClient(ctrl)
+	arc.EXPECT().GetLatestRelease("foo/bar", runtime.GOOS, runtime.GOARCH).
+		Return(nil, fmt.Errorf("%w: foo/bar", pkg.ErrNotFound))
+
+	cache := cache{
+		Packages:  map[string][]string{},
+		arcClient: arc,
+	}
+
+	if _, err := cache.InstallPkg("foo/bar"); !errors.Is(err, pkg.ErrNotFound) {
+		t.Errorf("InstallPkg should have failed with ErrNotFound (got %v)", err)
+	}
+}
@@ -0,0 +1,77 @@
+package changelog
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+)
+
+// Add adds the given change to the latest release of the control directory located at path
+func Add(path, change string) error {
+	if strings.TrimSpace(change) == "" {
+		return errors.New("empty change")
+	}
+
+	c, err := pkg.ReadCtrlChangelog(path)
+	if err != nil {
+		return err
+	}
+
+	if err := c.AddChange(change); err != nil {
+		return err
+	}
+
+	return pkg.WriteCtrlChangelog(path, c)
+}
+
+// Bump adds a new packaging revision of the latest upstream version
+// to the control directory located at path, and returns its version
+func Bump(path, uploader string, changes []string) (string, error) {
+	c, err := pkg.ReadCtrlChangelog(path)
+	if err != nil {
+		return "", err
+	}
+
+	last, err := c.LastRelease()
+	if err != nil {
+		return "", err
+	}
+
+	version, err := pkg.NextRevision(last.Version)
+	if err != nil {
+		return "", err
+	}
+	if len(changes) == 0 {
+		changes = []string{"New packaging revision"}
+	}
+
+	return version, addRelease(path, c, version, uploader, changes)
+}
+
+// NewUpstream adds the initial release of the given upstream version
+// to the control directory located at path, and returns its version
+func NewUpstream(path, upstreamVersion, uploader string, changes []string) (string, error) {
+	c, err := pkg.ReadCtrlChangelog(path)
+	if err != nil {
+		return "", err
+	}
+
+	// Remove any leading v since we doesn't want it in gopkg archive
+	upstreamVersion = strings.TrimPrefix(upstreamVersion, "v")
+	if len(changes) == 0 {
+		changes = []string{fmt.Sprintf("New upstream release %s", upstreamVersion)}
+	}
+
+	version := fmt.Sprintf("%s-1", upstreamVersion)
+	return version, addRelease(path, c, version, uploader, changes)
+}
+
+func addRelease(path string, c pkg.Changelog, version, uploader string, changes []string) error {
+	if err := c.AddRelease(version, uploader, changes); err != nil {
+		return err
+	}
+
+	return pkg.WriteCtrlChangelog(path, c)
+}
@@ -0,0 +1,71 @@
+package changelog
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+)
+
+func TestChangelog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkg_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	uploader := "John Doe <john@doe.com>"
+	if err := pkg.CreateCtrlDirectory(dir, "1.0.0", uploader, pkg.ControlMeta{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Add(dir, "Fix build on windows"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Add(dir, " "); err == nil {
+		t.Error("Add should have failed")
+	}
+
+	version, err := Bump(dir, uploader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "1.0.0-2" {
+		t.Errorf("wrong version: %s", version)
+	}
+
+	version, err = NewUpstream(dir, "v1.1.0", uploader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "1.1.0-1" {
+		t.Errorf("wrong version: %s", version)
+	}
+
+	// versions must increase
+	if _, err := NewUpstream(dir, "1.0.5", uploader, nil); err == nil {
+		t.Error("NewUpstream should have failed")
+	}
+
+	c, err := pkg.ReadCtrlChangelog(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Releases) != 3 {
+		t.Fatalf("wrong number of releases: %d", len(c.Releases))
+	}
+	if changes := c.Releases[0].Changes; len(changes) != 2 || changes[1] != "Fix build on windows" {
+		t.Errorf("wrong changes: %v", changes)
+	}
+	if changes := c.Releases[2].Changes; len(changes) != 1 || changes[0] != "New upstream release 1.1.0" {
+		t.Errorf("wrong changes: %v", changes)
+	}
+	for _, release := range c.Releases {
+		if release.Uploader != uploader || release.Date.IsZero() {
+			t.Errorf("wrong release: %+v", release)
+		}
+	}
+}
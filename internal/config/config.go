@@ -5,10 +5,12 @@ import (
 	"io/ioutil"
 	"os/user"
 	"path/filepath"
+	"time"
 
+	"github.com/go-pkg-org/gopkg/internal/util"
 	"github.com/go-pkg-org/gopkg/internal/util/file"
 	"github.com/kelseyhightower/envconfig"
-	"gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
 )
 
 // GoPkgDir is the directory where gopkg meta files are placed.
@@ -23,6 +25,20 @@ type Maintainer struct {
 	SigningKey string `yaml:"signing_key" envconfig:"signing_key"`
 }
 
+// HTTP is the object containing the HTTP client configuration.
+// Proxies are read from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY env variables.
+type HTTP struct {
+	// Timeout applies to connecting and waiting for response headers (not to the body download)
+	Timeout time.Duration `yaml:"timeout" envconfig:"timeout"`
+	// Retries is the number of retries on transient errors
+	Retries int `yaml:"retries" envconfig:"retries"`
+	// CABundle is the path to a PEM bundle of additional trusted CAs
+	CABundle string `yaml:"ca_bundle,omitempty" envconfig:"ca_bundle"`
+	// ClientCert & ClientKey are the paths to a PEM client certificate and its key
+	ClientCert string `yaml:"client_cert,omitempty" envconfig:"client_cert"`
+	ClientKey  string `yaml:"client_key,omitempty" envconfig:"client_key"`
+}
+
 // Config is the root object containg the configuration file.
 type Config struct {
 	BinDir      string     `yaml:"bin_dir" envconfig:"bin_dir"`
@@ -31,6 +47,8 @@ type Config struct {
 	SrcDir      string     `yaml:"src_dir"  envconfig:"src_dir"`
 	ArchiveAddr string     `yaml:"archive_addr"  envconfig:"archive_addr"`
 	UploadAddr  string     `yaml:"upload_addr"  envconfig:"upload_addr"`
+	DownloadDir string     `yaml:"download_dir" envconfig:"download_dir"`
+	HTTP        HTTP       `yaml:"http" envconfig:"http"`
 }
 
 // load loads the configuration file from the users home directory.
@@ -47,7 +65,7 @@ func (c *Config) load() error {
 			return err
 		}
 
-		err = yaml.Unmarshal([]byte(out), &c)
+		err = yaml.Unmarshal(out, c)
 		if err != nil {
 			return err
 		}
@@ -73,7 +91,7 @@ func (c *Config) create() error {
 		return nil
 	}
 
-	buf, err := yaml.Marshal(c)
+	buf, err := util.EncodeYAML(c)
 	if err != nil {
 		return err
 	}
@@ -93,6 +111,11 @@ func Default() (*Config, error) {
 		BinDir:      filepath.Join(u.HomeDir, GoPkgDir, "bin"),
 		CachePath:   filepath.Join(u.HomeDir, GoPkgDir, "cache.json"),
 		SrcDir:      filepath.Join(u.HomeDir, GoPkgDir, "src"),
+		DownloadDir: filepath.Join(u.HomeDir, GoPkgDir, "downloads"),
+		HTTP: HTTP{
+			Timeout: 30 * time.Second,
+			Retries: 3,
+		},
 	}
 
 	if err := c.create(); err != nil {
@@ -0,0 +1,226 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-pkg-org/gopkg/internal/archive"
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+	"github.com/go-pkg-org/gopkg/internal/util"
+)
+
+// maxTextSize is the maximum size of the files displayed as text diffs
+const maxTextSize = 1 << 20
+
+// entry is a package entry with its content
+type entry struct {
+	header  pkg.Header
+	content []byte
+}
+
+// Open opens the package referenced by ref, either a package file
+// or alias@version fetched from the archive
+func Open(ref string, arcClient archive.Client) (pkg.File, error) {
+	if strings.HasSuffix(ref, "."+pkg.FileExt) {
+		return pkg.OpenFile(ref)
+	}
+	if _, err := os.Stat(ref); err == nil {
+		return pkg.OpenFile(ref)
+	}
+
+	parts := strings.SplitN(ref, "@", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid package reference %s (expected file.pkg or alias@version)", ref)
+	}
+
+	return arcClient.GetRelease(parts[0], parts[1], runtime.GOOS, runtime.GOARCH)
+}
+
+// Diff writes the differences between the old & new packages to w
+// if stat is true only a summary of the changed files is written
+func Diff(oldPkg, newPkg pkg.File, w io.Writer, stat bool) error {
+	oldEntries, err := readEntries(oldPkg)
+	if err != nil {
+		return err
+	}
+	newEntries, err := readEntries(newPkg)
+	if err != nil {
+		return err
+	}
+
+	oldMeta, err := metadataLines(oldPkg)
+	if err != nil {
+		return err
+	}
+	newMeta, err := metadataLines(newPkg)
+	if err != nil {
+		return err
+	}
+
+	names := map[string]bool{}
+	for name := range oldEntries {
+		names[name] = true
+	}
+	for name := range newEntries {
+		names[name] = true
+	}
+
+	var sorted []string
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var stats []fileStat
+	var added, removed, changed []string
+	for _, name := range sorted {
+		oldEntry, inOld := oldEntries[name]
+		newEntry, inNew := newEntries[name]
+
+		switch {
+		case !inOld:
+			added = append(added, name)
+		case !inNew:
+			removed = append(removed, name)
+		case !sameEntry(oldEntry, newEntry):
+			changed = append(changed, name)
+		default:
+			continue
+		}
+
+		stats = append(stats, newFileStat(name, oldEntry, newEntry))
+	}
+
+	metaEdits := diffLines(oldMeta, newMeta)
+
+	if stat {
+		if hasChanges(metaEdits) {
+			metaStat := fileStat{name: "package.yaml", edits: metaEdits}
+			metaStat.insertions, metaStat.deletions = countChanges(metaEdits)
+			stats = append([]fileStat{metaStat}, stats...)
+		}
+
+		writeStat(w, stats)
+		return nil
+	}
+
+	if hasChanges(metaEdits) {
+		fmt.Fprintf(w, "Metadata:\n")
+		writeUnified(w, "a/package.yaml", "b/package.yaml", metaEdits)
+		fmt.Fprintln(w)
+	}
+
+	for _, name := range added {
+		fmt.Fprintf(w, "Added:   %s\n", describe(newEntries[name].header))
+	}
+	for _, name := range removed {
+		fmt.Fprintf(w, "Removed: %s\n", describe(oldEntries[name].header))
+	}
+	for _, name := range changed {
+		oldHeader, newHeader := oldEntries[name].header, newEntries[name].header
+		if oldHeader.Mode != newHeader.Mode {
+			fmt.Fprintf(w, "Changed: %s (mode %s -> %s)\n", name, oldHeader.Mode, newHeader.Mode)
+		} else {
+			fmt.Fprintf(w, "Changed: %s\n", describe(newHeader))
+		}
+	}
+
+	for _, s := range stats {
+		if s.binary || (s.insertions == 0 && s.deletions == 0) {
+			continue
+		}
+
+		fmt.Fprintln(w)
+		writeUnified(w, "a/"+s.name, "b/"+s.name, s.edits)
+	}
+
+	return nil
+}
+
+// readEntries load the package entries, meta files excepted
+// control packages entries are stored under a versioned directory which is removed
+func readEntries(p pkg.File) (map[string]entry, error) {
+	_, metaErr := p.Metadata()
+	isControl := metaErr != nil
+
+	entries := map[string]entry{}
+	err := p.Walk(func(h pkg.Header, r io.Reader) error {
+		if pkg.IsMetaFile(h.Name) {
+			return nil
+		}
+
+		if isControl {
+			parts := strings.SplitN(h.Name, "/", 2)
+			if len(parts) != 2 {
+				return nil
+			}
+			h.Name = parts[1]
+		}
+
+		var content []byte
+		if h.Type == pkg.RegularFile {
+			b, err := ioutil.ReadAll(r)
+			if err != nil {
+				return err
+			}
+			content = b
+		}
+
+		entries[h.Name] = entry{header: h, content: content}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// metadataLines returns the package metadata as yaml lines (nil for control packages)
+func metadataLines(p pkg.File) ([]string, error) {
+	meta, err := p.Metadata()
+	if err != nil {
+		return nil, nil
+	}
+
+	b, err := util.EncodeYAML(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	return splitLines(string(b)), nil
+}
+
+func sameEntry(a, b entry) bool {
+	return a.header.Type == b.header.Type && a.header.Mode == b.header.Mode &&
+		a.header.Linkname == b.header.Linkname && bytes.Equal(a.content, b.content)
+}
+
+func hasChanges(edits []edit) bool {
+	insertions, deletions := countChanges(edits)
+	return insertions+deletions > 0
+}
+
+// describe returns a short description of the entry
+func describe(h pkg.Header) string {
+	switch h.Type {
+	case pkg.Directory:
+		return h.Name + "/"
+	case pkg.Symlink:
+		return fmt.Sprintf("%s -> %s", h.Name, h.Linkname)
+	default:
+		return fmt.Sprintf("%s (%d bytes)", h.Name, h.Size)
+	}
+}
+
+// isText returns true if the content should be displayed as a text diff
+func isText(content []byte) bool {
+	return len(content) <= maxTextSize && utf8.Valid(content) && bytes.IndexByte(content, 0) == -1
+}
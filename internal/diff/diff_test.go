@@ -0,0 +1,104 @@
+package diff
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+)
+
+func TestDiff(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gopkg_*")
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	oldPkg := writePackage(t, filepath.Join(dir, "old"), map[string]string{
+		"package.yaml":       "alias: foo/bar\nrelease_version: 1.0.0-1\n",
+		"foo/bar/main.go":    "package main\n\nfunc main() {\n}\n",
+		"foo/bar/removed.go": "package main\n",
+		"foo/bar/logo.png":   "\x89PNG\x00\x01",
+	})
+	newPkg := writePackage(t, filepath.Join(dir, "new"), map[string]string{
+		"package.yaml":     "alias: foo/bar\nrelease_version: 1.1.0-1\n",
+		"foo/bar/main.go":  "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n",
+		"foo/bar/added.go": "package main\n",
+		"foo/bar/logo.png": "\x89PNG\x00\x02",
+	})
+
+	var buf bytes.Buffer
+	if err := Diff(oldPkg, newPkg, &buf, false); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	for _, expected := range []string{
+		"-release_version: 1.0.0-1\n+release_version: 1.1.0-1\n",
+		"Added:   foo/bar/added.go (13 bytes)",
+		"Removed: foo/bar/removed.go (13 bytes)",
+		"Changed: foo/bar/logo.png (6 bytes)",
+		"Changed: foo/bar/main.go",
+		"--- a/foo/bar/main.go\n+++ b/foo/bar/main.go\n@@ -1,4 +1,5 @@\n package main\n \n func main() {\n+\tprintln(\"hello\")\n }\n",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("output does not contain %q:\n%s", expected, out)
+		}
+	}
+	if strings.Contains(out, "a/foo/bar/logo.png") {
+		t.Errorf("binary files should not be displayed:\n%s", out)
+	}
+
+	buf.Reset()
+	if err := Diff(oldPkg, newPkg, &buf, true); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := ` package.yaml       | 2 +-
+ foo/bar/added.go   | 1 +
+ foo/bar/logo.png   | Bin 6 -> 6 bytes
+ foo/bar/main.go    | 1 +
+ foo/bar/removed.go | 1 -
+ 5 files changed, 3 insertions(+), 2 deletions(-)
+`
+	if buf.String() != expected {
+		t.Errorf("wrong stat:\n%s", buf.String())
+	}
+}
+
+func TestOpen(t *testing.T) {
+	if _, err := Open("foo", nil); err == nil {
+		t.Error("Open should have rejected invalid reference")
+	}
+	if _, err := Open("foo@", nil); err == nil {
+		t.Error("Open should have rejected invalid reference")
+	}
+}
+
+func writePackage(t *testing.T, dir string, files map[string]string) pkg.File {
+	var entries []pkg.Entry
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0750)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, pkg.Entry{FilePath: path, ArchivePath: name})
+	}
+
+	pkgPath := dir + ".pkg"
+	if err := pkg.Write(pkgPath, entries, true, pkg.NoCompression, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := pkg.OpenFile(pkgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
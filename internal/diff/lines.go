@@ -0,0 +1,229 @@
+package diff
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines displayed around changes
+const contextLines = 3
+
+// maxEditDistance bound the diff computation, files with more changes are displayed as fully replaced
+const maxEditDistance = 2000
+
+// opKind is the kind of a line edit
+type opKind byte
+
+const (
+	opEqual  opKind = ' '
+	opDelete opKind = '-'
+	opInsert opKind = '+'
+)
+
+// edit is a line of the edit script transforming a into b
+type edit struct {
+	kind opKind
+	line string
+	// aLine & bLine are the (0 based) line positions in a & b
+	aLine, bLine int
+}
+
+// splitLines split content into lines, without the line terminators
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// diffLines returns the shortest edit script transforming a into b
+// using the Myers algorithm
+func diffLines(a, b []string) []edit {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil
+	}
+
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		if d > maxEditDistance {
+			return replaceAll(a, b)
+		}
+
+		// only the diagonals reachable at this step are needed to backtrack
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // insertion
+			} else {
+				x = v[offset+k-1] + 1 // deletion
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return backtrack(trace, a, b)
+			}
+		}
+	}
+
+	return replaceAll(a, b)
+}
+
+// backtrack walk the trace backward to build the edit script
+// trace[d] holds the furthest x reached on diagonals -d-1 to d+1 before step d
+func backtrack(trace [][]int, a, b []string) []edit {
+	var edits []edit
+	x, y := len(a), len(b)
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[k-1+d+1] < v[k+1+d+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[prevK+d+1]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, edit{kind: opEqual, line: a[x], aLine: x, bLine: y})
+		}
+
+		if d > 0 {
+			if x == prevX {
+				y--
+				edits = append(edits, edit{kind: opInsert, line: b[y], aLine: x, bLine: y})
+			} else {
+				x--
+				edits = append(edits, edit{kind: opDelete, line: a[x], aLine: x, bLine: y})
+			}
+		}
+	}
+
+	// edits have been collected from the end
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+
+	return edits
+}
+
+// replaceAll returns the edit script deleting the whole a & inserting the whole b
+func replaceAll(a, b []string) []edit {
+	var edits []edit
+	for i, line := range a {
+		edits = append(edits, edit{kind: opDelete, line: line, aLine: i})
+	}
+	for i, line := range b {
+		edits = append(edits, edit{kind: opInsert, line: line, aLine: len(a), bLine: i})
+	}
+
+	return edits
+}
+
+// countChanges returns the number of inserted & deleted lines
+func countChanges(edits []edit) (int, int) {
+	insertions, deletions := 0, 0
+	for _, e := range edits {
+		switch e.kind {
+		case opInsert:
+			insertions++
+		case opDelete:
+			deletions++
+		}
+	}
+
+	return insertions, deletions
+}
+
+// writeUnified writes the edits as an unified diff
+func writeUnified(w io.Writer, oldName, newName string, edits []edit) {
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", oldName, newName)
+
+	for _, hunk := range hunks(edits) {
+		aStart, bStart := hunk[0].aLine, hunk[0].bLine
+		aCount, bCount := 0, 0
+		for _, e := range hunk {
+			if e.kind != opInsert {
+				aCount++
+			}
+			if e.kind != opDelete {
+				bCount++
+			}
+		}
+
+		fmt.Fprintf(w, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+		for _, e := range hunk {
+			fmt.Fprintf(w, "%c%s\n", e.kind, e.line)
+		}
+	}
+}
+
+// hunks group the changes with their surrounding context
+func hunks(edits []edit) [][]edit {
+	var result [][]edit
+
+	start, end := -1, -1
+	for i, e := range edits {
+		if e.kind == opEqual {
+			continue
+		}
+
+		from := i - contextLines
+		if from < 0 {
+			from = 0
+		}
+
+		// the change is too far from the current hunk: flush it
+		if start != -1 && from > end {
+			result = append(result, edits[start:end])
+			start = -1
+		}
+		if start == -1 {
+			start = from
+		}
+
+		end = i + contextLines + 1
+		if end > len(edits) {
+			end = len(edits)
+		}
+	}
+
+	if start != -1 {
+		result = append(result, edits[start:end])
+	}
+
+	return result
+}
+
+// hunkRange format the (0 based) start line & count as displayed in hunk headers
+func hunkRange(start, count int) string {
+	if count == 0 {
+		// empty ranges refer to the line before
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
@@ -0,0 +1,73 @@
+package diff
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"", ""},
+		{"a\nb\nc\n", "a\nb\nc\n"},
+		{"", "a\nb\n"},
+		{"a\nb\n", ""},
+		{"a\nb\nc\nd\n", "a\nc\nd\ne\n"},
+		{"a\nb\nc\na\nb\nb\na\n", "c\nb\na\nb\na\nc\n"},
+	}
+
+	for _, test := range tests {
+		a, b := splitLines(test.a), splitLines(test.b)
+		edits := diffLines(a, b)
+
+		// applying the edits must give back both sides
+		var gotA, gotB []string
+		for _, e := range edits {
+			if e.kind != opInsert {
+				gotA = append(gotA, e.line)
+			}
+			if e.kind != opDelete {
+				gotB = append(gotB, e.line)
+			}
+		}
+		if strings.Join(gotA, "\n") != strings.Join(a, "\n") || strings.Join(gotB, "\n") != strings.Join(b, "\n") {
+			t.Errorf("wrong edits for %q -> %q: %v", test.a, test.b, edits)
+		}
+	}
+
+	// the edit script must be the shortest one
+	insertions, deletions := countChanges(diffLines(splitLines("a\nb\nc\na\nb\nb\na\n"), splitLines("c\nb\na\nb\na\nc\n")))
+	if insertions+deletions != 5 {
+		t.Errorf("edit script is not the shortest one (%d insertions, %d deletions)", insertions, deletions)
+	}
+}
+
+func TestWriteUnified(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n"
+
+	var buf bytes.Buffer
+	writeUnified(&buf, "a/file", "b/file", diffLines(splitLines(a), splitLines(b)))
+
+	expected := `--- a/file
++++ b/file
+@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+ 6
+@@ -13,3 +13,4 @@
+ 13
+ 14
+ 15
++16
+`
+	if buf.String() != expected {
+		t.Errorf("wrong unified diff:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,91 @@
+package diff
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+)
+
+// maxBarWidth is the maximum width of the +/- bar displayed by --stat
+const maxBarWidth = 50
+
+// fileStat summarize the changes of a package entry
+type fileStat struct {
+	name       string
+	edits      []edit
+	insertions int
+	deletions  int
+	// binary is true when the entry content cannot be displayed as text
+	binary           bool
+	oldSize, newSize int64
+}
+
+func newFileStat(name string, oldEntry, newEntry entry) fileStat {
+	s := fileStat{name: name, oldSize: int64(len(oldEntry.content)), newSize: int64(len(newEntry.content))}
+
+	// only regular files have a content to compare
+	if oldEntry.header.Type != pkg.RegularFile && newEntry.header.Type != pkg.RegularFile {
+		return s
+	}
+
+	if !isText(oldEntry.content) || !isText(newEntry.content) {
+		s.binary = true
+		return s
+	}
+
+	s.edits = diffLines(splitLines(string(oldEntry.content)), splitLines(string(newEntry.content)))
+	s.insertions, s.deletions = countChanges(s.edits)
+	return s
+}
+
+// writeStat writes a summary of the changes (git diff --stat like)
+func writeStat(w io.Writer, stats []fileStat) {
+	nameWidth, maxChanges := 0, 0
+	totalInsertions, totalDeletions := 0, 0
+	for _, s := range stats {
+		if len(s.name) > nameWidth {
+			nameWidth = len(s.name)
+		}
+		if s.insertions+s.deletions > maxChanges {
+			maxChanges = s.insertions + s.deletions
+		}
+		totalInsertions += s.insertions
+		totalDeletions += s.deletions
+	}
+
+	for _, s := range stats {
+		if s.binary {
+			fmt.Fprintf(w, " %-*s | Bin %d -> %d bytes\n", nameWidth, s.name, s.oldSize, s.newSize)
+			continue
+		}
+
+		insertions, deletions := s.insertions, s.deletions
+		// scale the bar down if needed
+		if maxChanges > maxBarWidth {
+			insertions = scale(insertions, maxChanges)
+			deletions = scale(deletions, maxChanges)
+		}
+
+		fmt.Fprintf(w, " %-*s | %d %s%s\n", nameWidth, s.name, s.insertions+s.deletions,
+			strings.Repeat("+", insertions), strings.Repeat("-", deletions))
+	}
+
+	fmt.Fprintf(w, " %d files changed, %d insertions(+), %d deletions(-)\n",
+		len(stats), totalInsertions, totalDeletions)
+}
+
+// scale the given changes count to the bar width, keeping at least one char for any change
+func scale(changes, maxChanges int) int {
+	if changes == 0 {
+		return 0
+	}
+
+	scaled := changes * maxBarWidth / maxChanges
+	if scaled == 0 {
+		return 1
+	}
+
+	return scaled
+}
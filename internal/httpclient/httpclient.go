@@ -0,0 +1,172 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-pkg-org/gopkg/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// maxBackoff is the maximum delay between two attempts
+const maxBackoff = 30 * time.Second
+
+// New create a brand new HTTP client using given configuration
+// the client retries transient errors with exponential backoff
+// and identifies itself using userAgent
+func New(conf config.HTTP, userAgent string) (*http.Client, error) {
+	tlsConfig, err := getTLSConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   conf.Timeout,
+		KeepAlive: 30 * time.Second,
+	}
+
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   conf.Timeout,
+		ResponseHeaderTimeout: conf.Timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+	}
+
+	return &http.Client{
+		Transport: &retryTransport{
+			next:      transport,
+			retries:   conf.Retries,
+			backoff:   time.Second,
+			userAgent: userAgent,
+		},
+	}, nil
+}
+
+func getTLSConfig(conf config.HTTP) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	if conf.CABundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+
+		b, err := ioutil.ReadFile(conf.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("error while reading CA bundle: %s", err)
+		}
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no certificate found in CA bundle %s", conf.CABundle)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if conf.ClientCert != "" || conf.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(conf.ClientCert, conf.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("error while loading client certificate: %s", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// retryTransport is an http.RoundTripper retrying transient errors
+type retryTransport struct {
+	next      http.RoundTripper
+	retries   int
+	backoff   time.Duration
+	userAgent string
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTripper must not modify the original request
+	req = req.Clone(req.Context())
+	if t.userAgent != "" && req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			// Rewind the request body
+			if req.Body != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				req.Body = body
+			}
+		}
+
+		resp, err := t.next.RoundTrip(req)
+		if attempt >= t.retries || !isRetryable(req, resp, err) {
+			return resp, err
+		}
+
+		if err != nil {
+			log.Debug().Str("url", req.URL.String()).Err(err).Int("attempt", attempt+1).Msg("Retrying request")
+		} else {
+			log.Debug().Str("url", req.URL.String()).Str("status", resp.Status).Int("attempt", attempt+1).Msg("Retrying request")
+			// Drain body so the connection can be reused
+			_, _ = ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(t.getBackoff(attempt)):
+		}
+	}
+}
+
+// getBackoff returns the delay to wait before attempt+1
+func (t *retryTransport) getBackoff(attempt int) time.Duration {
+	d := t.backoff << uint(attempt)
+	if d <= 0 || d > maxBackoff {
+		return maxBackoff
+	}
+
+	return d
+}
+
+// isRetryable determinate if the request has failed because of a transient error
+// only idempotent requests are replayed, unless the server rejected them before processing (429)
+func isRetryable(req *http.Request, resp *http.Response, err error) bool {
+	// Cannot replay the request
+	if req.Body != nil && req.GetBody == nil {
+		return false
+	}
+
+	if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	// the server may have processed the request
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,142 @@
+package httpclient
+
+import (
+	"github.com/go-pkg-org/gopkg/internal/config"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNew_Retry(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("User-Agent") != "gopkg/test" {
+			t.Errorf("wrong user agent: %s", r.Header.Get("User-Agent"))
+		}
+
+		b, _ := ioutil.ReadAll(r.Body)
+		if string(b) != "hello" {
+			t.Errorf("wrong body: %s", b)
+		}
+
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("world"))
+	}))
+	defer srv.Close()
+
+	c, err := New(config.HTTP{Timeout: time.Second, Retries: 3}, "gopkg/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Transport.(*retryTransport).backoff = time.Millisecond
+
+	req, err := http.NewRequest("GET", srv.URL, strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("wrong status: %s", resp.Status)
+	}
+	if calls != 3 {
+		t.Errorf("wrong number of calls: %d", calls)
+	}
+}
+
+func TestNew_NoRetry(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	c, err := New(config.HTTP{Timeout: time.Second, Retries: 3}, "gopkg/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Transport.(*retryTransport).backoff = time.Millisecond
+
+	resp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("wrong status: %s", resp.Status)
+	}
+	if calls != 1 {
+		t.Errorf("wrong number of calls: %d", calls)
+	}
+}
+
+func TestNew_RetryPost(t *testing.T) {
+	calls := 0
+	status := http.StatusServiceUnavailable
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(status)
+		}
+	}))
+	defer srv.Close()
+
+	c, err := New(config.HTTP{Timeout: time.Second, Retries: 3}, "gopkg/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Transport.(*retryTransport).backoff = time.Millisecond
+
+	// the server may have processed the request
+	resp, err := c.Post(srv.URL, "text/plain", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("wrong status: %s", resp.Status)
+	}
+	if calls != 1 {
+		t.Errorf("wrong number of calls: %d", calls)
+	}
+
+	// the request has been rejected before processing
+	calls = 0
+	status = http.StatusTooManyRequests
+	resp, err = c.Post(srv.URL, "text/plain", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("wrong status: %s", resp.Status)
+	}
+	if calls != 3 {
+		t.Errorf("wrong number of calls: %d", calls)
+	}
+}
+
+func TestNew_InvalidCABundle(t *testing.T) {
+	f, _ := ioutil.TempFile("", "")
+	f.WriteString("not a certificate")
+	f.Close()
+
+	if _, err := New(config.HTTP{CABundle: f.Name()}, ""); err == nil {
+		t.Error("New should have failed with invalid CA bundle")
+	}
+}
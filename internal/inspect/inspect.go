@@ -0,0 +1,132 @@
+package inspect
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+	"github.com/go-pkg-org/gopkg/internal/sign"
+	"github.com/go-pkg-org/gopkg/internal/util"
+	"github.com/rs/zerolog/log"
+)
+
+// Inspect prints the package located at path (metadata, type, signature & files) to w
+func Inspect(pkgPath string, w io.Writer) error {
+	f, err := pkg.OpenFile(pkgPath)
+	if err != nil {
+		return err
+	}
+
+	fileName := filepath.Base(pkgPath)
+	pkgType := "unknown"
+	if _, _, _, _, t, err := pkg.ParseFileName(fileName); err == nil {
+		pkgType = string(t)
+	}
+
+	fmt.Fprintf(w, "Package:   %s\n", fileName)
+	fmt.Fprintf(w, "Type:      %s\n", pkgType)
+
+	// collect the entries first to display the format version
+	var headers []pkg.Header
+	format := "legacy"
+	if err := f.Walk(func(h pkg.Header, r io.Reader) error {
+		if h.Name == pkg.FormatFile {
+			b, err := ioutil.ReadAll(r)
+			if err != nil {
+				return err
+			}
+			format = strings.TrimSpace(string(b))
+		}
+
+		headers = append(headers, h)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Format:    %s\n", format)
+	fmt.Fprintf(w, "Signature: %s\n", signatureStatus(pkgPath))
+
+	// control packages have no package.yaml
+	if meta, err := f.Metadata(); err == nil {
+		b, err := util.EncodeYAML(meta)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "\nMetadata:\n")
+		for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
+
+	fmt.Fprintf(w, "\nFiles:\n")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, h := range headers {
+		name := h.Name
+		switch h.Type {
+		case pkg.Directory:
+			name += "/"
+		case pkg.Symlink:
+			name = fmt.Sprintf("%s -> %s", name, h.Linkname)
+		}
+
+		fmt.Fprintf(tw, "  %s\t%d\t%s\n", entryMode(h), h.Size, name)
+	}
+
+	return tw.Flush()
+}
+
+// Extract safely extracts the whole package located at path into dir
+func Extract(pkgPath, dir string) error {
+	f, err := pkg.OpenFile(pkgPath)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return err
+	}
+
+	files, err := pkg.Extract(f, dir, nil)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("package", pkgPath).Str("dir", dir).Int("files", len(files)).Msg("Successfully extracted package")
+	return nil
+}
+
+// signatureStatus check the signature stored next to the package (<pkg>.asc)
+func signatureStatus(pkgPath string) string {
+	ascPath := pkgPath + ".asc"
+	if _, err := os.Stat(ascPath); errors.Is(err, os.ErrNotExist) {
+		return "none"
+	}
+
+	signer, err := sign.Verify(pkgPath, ascPath)
+	if err != nil {
+		return fmt.Sprintf("INVALID (%s)", err)
+	}
+
+	return fmt.Sprintf("valid (%s)", signer)
+}
+
+// entryMode returns a ls like representation of the entry mode
+func entryMode(h pkg.Header) string {
+	mode := h.Mode
+	switch h.Type {
+	case pkg.Directory:
+		mode |= os.ModeDir
+	case pkg.Symlink:
+		mode |= os.ModeSymlink
+	}
+
+	return mode.String()
+}
@@ -0,0 +1,87 @@
+package inspect
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+)
+
+func TestInspect(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gopkg_*")
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	pkgPath := writePackage(t, dir)
+
+	var buf bytes.Buffer
+	if err := Inspect(pkgPath, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	for _, expected := range []string{
+		"Type:      binary",
+		"Format:    " + pkg.FormatVersion,
+		"Signature: none",
+		"alias: foo-bar",
+		"target_os: linux",
+		"-rwxr-xr-x  6",
+		"bin/foo-bar",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("output does not contain %q:\n%s", expected, out)
+		}
+	}
+
+	// a signature which does not validate
+	ioutil.WriteFile(pkgPath+".asc", []byte("garbage"), 0640)
+	buf.Reset()
+	if err := Inspect(pkgPath, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "Signature: INVALID") {
+		t.Errorf("signature should be invalid:\n%s", buf.String())
+	}
+}
+
+func TestExtract(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gopkg_*")
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	pkgPath := writePackage(t, dir)
+
+	if err := Extract(pkgPath, filepath.Join(dir, "out")); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "out", "bin", "foo-bar"))
+	if err != nil || string(b) != "binary" {
+		t.Errorf("binary has not been extracted (%v)", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out", "package.yaml")); err != nil {
+		t.Errorf("package.yaml has not been extracted (%v)", err)
+	}
+}
+
+func writePackage(t *testing.T, dir string) string {
+	ioutil.WriteFile(filepath.Join(dir, "package.yaml"),
+		[]byte("alias: foo-bar\nmain: main.go\ntarget_os: linux\ntarget_arch: amd64\n"), 0640)
+	ioutil.WriteFile(filepath.Join(dir, "foo-bar"), []byte("binary"), 0755)
+
+	pkgPath := filepath.Join(dir, "foo-bar_1.0.0-1_linux_amd64.pkg")
+	if err := pkg.Write(pkgPath, []pkg.Entry{
+		{FilePath: filepath.Join(dir, "package.yaml"), ArchivePath: "package.yaml"},
+		{FilePath: filepath.Join(dir, "foo-bar"), ArchivePath: "bin/foo-bar"},
+	}, true, pkg.Gzip, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	return pkgPath
+}
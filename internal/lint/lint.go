@@ -0,0 +1,147 @@
+package lint
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+)
+
+// Severity is the severity of an issue
+type Severity string
+
+const (
+	// Warning issues should be fixed but does not prevent the package from being uploaded
+	Warning Severity = "warning"
+	// Error issues prevent the package from being uploaded
+	Error Severity = "error"
+)
+
+// Issue is a problem reported by a rule
+type Issue struct {
+	Rule     string
+	Severity Severity
+	Message  string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: [%s] %s", i.Severity, i.Rule, i.Message)
+}
+
+// Target is what the rules are checking, either a control directory or a source / binary package
+type Target struct {
+	// Control & Changelog are set when checking a control directory / package
+	Control   *pkg.ControlMeta
+	Changelog *pkg.Changelog
+	// Meta & FileName are set when checking a source / binary package
+	Meta     *pkg.Meta
+	FileName string
+	// Files are the root files of the control directory / source package
+	Files []string
+}
+
+// Rule is a check applied on a target
+type Rule struct {
+	Name  string
+	Check func(t Target) []Issue
+}
+
+// Lint applies all the rules on given target
+func Lint(t Target) []Issue {
+	var issues []Issue
+	for _, rule := range Rules {
+		for _, issue := range rule.Check(t) {
+			issue.Rule = rule.Name
+			issues = append(issues, issue)
+		}
+	}
+
+	return issues
+}
+
+// HasErrors returns true if one of the issues is an error
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == Error {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Directory lint the control directory located at path
+func Directory(path string) ([]Issue, error) {
+	m, c, err := pkg.ReadCtrlDirectory(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		files = append(files, entry.Name())
+	}
+
+	return Lint(Target{Control: &m, Changelog: &c, Files: files}), nil
+}
+
+// File lint the given package, fileName is the package file name
+// control packages are extracted into a temporary directory to be checked
+func File(f pkg.File, fileName string) ([]Issue, error) {
+	if _, _, _, _, pkgType, err := pkg.ParseFileName(fileName); err == nil && pkgType == pkg.Control {
+		return controlPackage(f)
+	}
+
+	meta, err := f.Metadata()
+	if err != nil {
+		return nil, err
+	}
+
+	// root files of the source code
+	var files []string
+	if err := f.Walk(func(h pkg.Header, r io.Reader) error {
+		if dir, file := path.Split(h.Name); strings.TrimSuffix(dir, "/") == meta.Alias {
+			files = append(files, file)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return Lint(Target{Meta: &meta, FileName: fileName, Files: files}), nil
+}
+
+func controlPackage(f pkg.File) ([]Issue, error) {
+	dir, err := ioutil.TempDir("", "gopkg-lint-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := pkg.Extract(f, dir, func(h *pkg.Header) bool {
+		return !pkg.IsMetaFile(h.Name)
+	}); err != nil {
+		return nil, err
+	}
+
+	// the control directory is the single root directory of the package
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) != 1 || !entries[0].IsDir() {
+		return nil, fmt.Errorf("%w: invalid control package layout", pkg.ErrCorruptPackage)
+	}
+
+	return Directory(filepath.Join(dir, entries[0].Name()))
+}
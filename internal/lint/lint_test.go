@@ -0,0 +1,116 @@
+package lint
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+)
+
+func TestLint_Control(t *testing.T) {
+	m := pkg.ControlMeta{
+		ImportPath:  "github.com/foo/bar",
+		Maintainers: []string{"John Doe <john@doe.com>", " <>"},
+		Packages: []pkg.Meta{
+			{Alias: "github.com/foo/bar/cmd/a", Main: "cmd/a/main.go", BinName: "bar", Description: "TODO",
+				Targets: map[string][]string{"linux": {"amd64"}}},
+			{Alias: "github.com/foo/bar/cmd/b", Main: "cmd/b/main.go", BinName: "bar", Description: "B tool",
+				Targets: map[string][]string{"linux": {"wasm"}}},
+			{Alias: "github.com/foo/bar/cmd/c", Main: "cmd/c/main.go", BinName: "c"},
+		},
+	}
+	c := pkg.Changelog{Releases: []pkg.Release{
+		{Version: "1.0.0-1", Uploader: "John Doe <john@doe.com>"},
+		{Version: "1.1.0", Uploader: "Jane Doe <jane@doe.com>"},
+	}}
+
+	issues := Lint(Target{Control: &m, Changelog: &c, Files: []string{"README.md"}})
+
+	expected := map[string]Severity{
+		"placeholder-description": Error,
+		"invalid-version":         Error,
+		"missing-targets":         Error,
+		"unsupported-target":      Warning,
+		"maintainers":             Error,
+		"binname-collision":       Error,
+		"missing-license":         Warning,
+	}
+	found := map[string]bool{}
+	for _, issue := range issues {
+		found[issue.Rule] = true
+		if severity, exist := expected[issue.Rule]; !exist {
+			t.Errorf("unexpected issue: %s", issue)
+		} else if issue.Rule != "placeholder-description" && issue.Rule != "maintainers" && issue.Severity != severity {
+			t.Errorf("wrong severity: %s", issue)
+		}
+	}
+	for rule := range expected {
+		if !found[rule] {
+			t.Errorf("rule %s has not been triggered", rule)
+		}
+	}
+
+	if !HasErrors(issues) {
+		t.Error("HasErrors should have returned true")
+	}
+}
+
+func TestLint_Clean(t *testing.T) {
+	m := pkg.ControlMeta{
+		ImportPath:  "github.com/foo/bar",
+		Maintainers: []string{"John Doe <john@doe.com>"},
+		Packages: []pkg.Meta{
+			{Alias: "github.com/foo/bar", Main: "main.go", BinName: "bar", Description: "Bar tool",
+				Targets: map[string][]string{"linux": {"amd64"}, "darwin": {"amd64"}}},
+		},
+	}
+	c := pkg.Changelog{Releases: []pkg.Release{{Version: "1.0.0-1", Uploader: "John Doe <john@doe.com>"}}}
+
+	if issues := Lint(Target{Control: &m, Changelog: &c, Files: []string{"LICENSE"}}); len(issues) != 0 {
+		t.Errorf("unexpected issues: %v", issues)
+	}
+}
+
+func TestFile(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gopkg_*")
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	ioutil.WriteFile(filepath.Join(dir, "package.yaml"),
+		[]byte("alias: github.com/foo/bar\nrelease_version: 1.0.0-1\n"), 0640)
+	ioutil.WriteFile(filepath.Join(dir, "LICENSE"), []byte("MIT"), 0640)
+
+	pkgPath := filepath.Join(dir, "github.com-foo-bar-src_1.0.0-1.pkg")
+	if err := pkg.Write(pkgPath, []pkg.Entry{
+		{FilePath: filepath.Join(dir, "package.yaml"), ArchivePath: "package.yaml"},
+		{FilePath: filepath.Join(dir, "LICENSE"), ArchivePath: "github.com/foo/bar/LICENSE"},
+	}, true, pkg.NoCompression, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := pkg.OpenFile(pkgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issues, err := File(f, filepath.Base(pkgPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 0 {
+		t.Errorf("unexpected issues: %v", issues)
+	}
+
+	// file name does not match the metadata
+	issues, err = File(f, "github.com-foo-bar-src_1.0.0-2.pkg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[0].Rule != "filename-mismatch" {
+		t.Errorf("wrong issues: %v", issues)
+	}
+}
@@ -0,0 +1,235 @@
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+)
+
+// Rules are the rules applied by Lint
+var Rules = []Rule{
+	{Name: "placeholder-description", Check: checkDescription},
+	{Name: "invalid-version", Check: checkVersions},
+	{Name: "missing-targets", Check: checkTargets},
+	{Name: "unsupported-target", Check: checkSupportedTargets},
+	{Name: "maintainers", Check: checkMaintainers},
+	{Name: "binname-collision", Check: checkBinNames},
+	{Name: "missing-license", Check: checkLicense},
+	{Name: "filename-mismatch", Check: checkFileName},
+}
+
+// licensePrefixes are the prefixes of the files considered as license
+var licensePrefixes = []string{"license", "licence", "copying", "unlicense"}
+
+// binaryPackages returns the binary packages described by the target
+func binaryPackages(t Target) []pkg.Meta {
+	if t.Control != nil {
+		return t.Control.Packages
+	}
+	if t.Meta != nil && !t.Meta.IsSource() {
+		return []pkg.Meta{*t.Meta}
+	}
+
+	return nil
+}
+
+func checkDescription(t Target) []Issue {
+	var issues []Issue
+	for _, p := range binaryPackages(t) {
+		switch strings.TrimSpace(p.Description) {
+		case "TODO":
+			issues = append(issues, Issue{Severity: Error,
+				Message: fmt.Sprintf("%s has a placeholder description", p.Alias)})
+		case "":
+			issues = append(issues, Issue{Severity: Warning,
+				Message: fmt.Sprintf("%s has no description", p.Alias)})
+		}
+	}
+
+	return issues
+}
+
+func checkVersions(t Target) []Issue {
+	var versions []string
+	if t.Changelog != nil {
+		for _, release := range t.Changelog.Releases {
+			versions = append(versions, release.Version)
+		}
+		if len(versions) == 0 {
+			return []Issue{{Severity: Error, Message: "changelog has no release"}}
+		}
+	}
+	if t.Meta != nil {
+		versions = append(versions, t.Meta.ReleaseVersion)
+	}
+
+	var issues []Issue
+	for _, version := range versions {
+		if err := pkg.ValidateVersion(version); err != nil {
+			issues = append(issues, Issue{Severity: Error, Message: err.Error()})
+		}
+	}
+
+	// versions can only be ordered once valid
+	if len(issues) == 0 && t.Changelog != nil {
+		if err := t.Changelog.Validate(); err != nil {
+			issues = append(issues, Issue{Severity: Error, Message: err.Error()})
+		}
+	}
+
+	return issues
+}
+
+func checkTargets(t Target) []Issue {
+	// binary packages files are built for a single target
+	if t.Control == nil {
+		return nil
+	}
+
+	var issues []Issue
+	for _, p := range t.Control.Packages {
+		if len(p.Targets) == 0 {
+			issues = append(issues, Issue{Severity: Error, Message: fmt.Sprintf("%s has no targets", p.Alias)})
+		}
+	}
+
+	return issues
+}
+
+func checkSupportedTargets(t Target) []Issue {
+	var issues []Issue
+	for _, p := range binaryPackages(t) {
+		targets := p.Targets
+		if t.Control == nil {
+			targets = map[string][]string{p.TargetOS: {p.TargetArch}}
+		}
+
+		var oses []string
+		for os := range targets {
+			oses = append(oses, os)
+		}
+		sort.Strings(oses)
+
+		for _, os := range oses {
+			for _, arch := range targets[os] {
+				// the package may be built by a newer Go toolchain
+				if !pkg.IsSupportedTarget(os, arch) {
+					issues = append(issues, Issue{Severity: Warning,
+						Message: fmt.Sprintf("%s targets platform %s/%s unknown to the Go toolchain", p.Alias, os, arch)})
+				}
+			}
+		}
+	}
+
+	return issues
+}
+
+func checkMaintainers(t Target) []Issue {
+	if t.Control == nil {
+		return nil
+	}
+
+	var issues []Issue
+	if len(t.Control.Maintainers) == 0 {
+		issues = append(issues, Issue{Severity: Error, Message: "package has no maintainers"})
+	}
+	for _, maintainer := range t.Control.Maintainers {
+		if !isValidMaintainer(maintainer) {
+			issues = append(issues, Issue{Severity: Error,
+				Message: fmt.Sprintf("invalid maintainer %q (expected Name <email>)", maintainer)})
+		}
+	}
+
+	if t.Changelog != nil && len(t.Changelog.Releases) > 0 {
+		uploader := t.Changelog.Releases[len(t.Changelog.Releases)-1].Uploader
+		isMaintainer := false
+		for _, maintainer := range t.Control.Maintainers {
+			if maintainer == uploader {
+				isMaintainer = true
+				break
+			}
+		}
+
+		if !isMaintainer {
+			issues = append(issues, Issue{Severity: Warning,
+				Message: fmt.Sprintf("changelog uploader %q is not a maintainer", uploader)})
+		}
+	}
+
+	return issues
+}
+
+// isValidMaintainer make sure the maintainer entry look like Name <email>
+// an unconfigured maintainer gives ` <>`
+func isValidMaintainer(maintainer string) bool {
+	i := strings.Index(maintainer, "<")
+	if i < 1 || !strings.HasSuffix(maintainer, ">") {
+		return false
+	}
+
+	name := strings.TrimSpace(maintainer[:i])
+	email := maintainer[i+1 : len(maintainer)-1]
+	return name != "" && strings.Contains(email, "@")
+}
+
+func checkBinNames(t Target) []Issue {
+	var issues []Issue
+	owners := map[string]string{}
+	for _, p := range binaryPackages(t) {
+		if p.BinName == "" {
+			issues = append(issues, Issue{Severity: Error, Message: fmt.Sprintf("%s has no binary name", p.Alias)})
+			continue
+		}
+
+		if owner, exist := owners[p.BinName]; exist {
+			issues = append(issues, Issue{Severity: Error,
+				Message: fmt.Sprintf("%s and %s both install binary %s", owner, p.Alias, p.BinName)})
+			continue
+		}
+		owners[p.BinName] = p.Alias
+	}
+
+	return issues
+}
+
+func checkLicense(t Target) []Issue {
+	// binary packages does not ship the source code
+	if t.Meta != nil && !t.Meta.IsSource() {
+		return nil
+	}
+
+	for _, file := range t.Files {
+		name := strings.ToLower(file)
+		for _, prefix := range licensePrefixes {
+			if strings.HasPrefix(name, prefix) {
+				return nil
+			}
+		}
+	}
+
+	return []Issue{{Severity: Warning, Message: "no license file found"}}
+}
+
+func checkFileName(t Target) []Issue {
+	if t.Meta == nil || t.FileName == "" {
+		return nil
+	}
+
+	pkgType := pkg.Binary
+	if t.Meta.IsSource() {
+		pkgType = pkg.Source
+	}
+
+	expected, err := pkg.GetFileName(t.Meta.Alias, t.Meta.ReleaseVersion, t.Meta.TargetOS, t.Meta.TargetArch, pkgType)
+	if err != nil {
+		return []Issue{{Severity: Error, Message: err.Error()}}
+	}
+	if expected != t.FileName {
+		return []Issue{{Severity: Error,
+			Message: fmt.Sprintf("file name %s does not match metadata (expected %s)", t.FileName, expected)}}
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package make
+
+import (
+	"github.com/go-pkg-org/gopkg/internal/archive"
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+	"github.com/rs/zerolog/log"
+)
+
+// getPackagedVersions returns the source packages versions available on the archive, by package name
+// the index is nil if the archive could not be reached
+func getPackagedVersions(index *archive.Index) map[string][]string {
+	versions := map[string][]string{}
+	if index == nil {
+		return versions
+	}
+
+	for alias, p := range index.Packages {
+		name := pkg.GetName(alias, true)
+		for version, releases := range p.Releases {
+			// source packages are not built for a target
+			for _, release := range releases {
+				if release.OS == "" && release.Arch == "" {
+					versions[name] = append(versions[name], version)
+					break
+				}
+			}
+		}
+	}
+
+	return versions
+}
+
+// checkPackagedDeps returns the dependencies not packaged on the archive
+// and the dependencies packaged with a version lower than the required one
+func checkPackagedDeps(deps []dependency, index *archive.Index) ([]dependency, []dependency) {
+	packagedVersions := getPackagedVersions(index)
+
+	var missing, outdated []dependency
+	for _, dep := range deps {
+		versions, exist := packagedVersions[dep.Name]
+		if !exist {
+			missing = append(missing, dep)
+			continue
+		}
+
+		if !isSatisfied(dep.Dependency, versions) {
+			outdated = append(outdated, dep)
+			continue
+		}
+
+		log.Debug().Str("dependency", dep.String()).Msg("Dependency already packaged")
+	}
+
+	return missing, outdated
+}
+
+// isSatisfied returns true if one of the packaged versions satisfy the dependency
+func isSatisfied(dep pkg.Dependency, versions []string) bool {
+	if dep.Version == "" {
+		return true
+	}
+
+	// requirements written before pseudo-versions were converted
+	minimum := cleanModuleVersion(dep.Version) + "-0"
+
+	for _, version := range versions {
+		// any revision of the required upstream version is fine
+		if pkg.ValidateVersion(version) == nil && pkg.CompareVersions(version, minimum) >= 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getIndex returns the archive index, or nil if the archive cannot be reached
+func getIndex(arcClient archive.Client) *archive.Index {
+	index, err := arcClient.GetIndex()
+	if err != nil {
+		log.Warn().Err(err).Msg("Unable to get archive index, all dependencies are considered missing")
+		return nil
+	}
+
+	return &index
+}
@@ -0,0 +1,72 @@
+package make
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-pkg-org/gopkg/internal/archive"
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+)
+
+func TestCheckPackagedDeps(t *testing.T) {
+	index := archive.NewIndex()
+	index.Packages["github.com/foo/bar"] = archive.Package{Releases: map[string][]archive.Release{
+		"1.2.0-1": {{}},
+		"1.3.0-2": {{}},
+	}}
+	index.Packages["github.com/foo/baz"] = archive.Package{Releases: map[string][]archive.Release{
+		"0.9.0-1": {{}},
+	}}
+	// binary packages only
+	index.Packages["github.com/foo/qux"] = archive.Package{Releases: map[string][]archive.Release{
+		"2.0.0-1": {{OS: "linux", Arch: "amd64"}},
+	}}
+
+	deps := []dependency{
+		{Dependency: pkg.Dependency{Name: "github.com-foo-bar-src", Version: "1.3.0"}},
+		{Dependency: pkg.Dependency{Name: "github.com-foo-baz-src", Version: "1.0.0"}},
+		{Dependency: pkg.Dependency{Name: "github.com-foo-baz-src"}},
+		{Dependency: pkg.Dependency{Name: "github.com-foo-qux-src", Version: "1.0.0"}},
+		{Dependency: pkg.Dependency{Name: "github.com-foo-quux-src"}},
+	}
+
+	missing, outdated := checkPackagedDeps(deps, &index)
+	if expected := []dependency{deps[3], deps[4]}; !reflect.DeepEqual(missing, expected) {
+		t.Errorf("wrong missing dependencies: %v", missing)
+	}
+	if expected := []dependency{deps[1]}; !reflect.DeepEqual(outdated, expected) {
+		t.Errorf("wrong outdated dependencies: %v", outdated)
+	}
+
+	// archive not reachable
+	missing, outdated = checkPackagedDeps(deps, nil)
+	if len(missing) != len(deps) || len(outdated) != 0 {
+		t.Errorf("all dependencies should be missing (got %v, %v)", missing, outdated)
+	}
+}
+
+func TestIsSatisfied(t *testing.T) {
+	tests := []struct {
+		version  string
+		versions []string
+		expected bool
+	}{
+		{"", nil, true},
+		{"1.2.0", []string{"1.2.0-1"}, true},
+		{"1.2.0", []string{"1.1.9-3", "1.2.0-1"}, true},
+		{"1.2.0", []string{"1.1.9-3"}, false},
+		{"0.0~git202001011200", []string{"0.0~git202001011200-1"}, true},
+		{"0.0~git202001011200", []string{"0.0~git201912312359-1"}, false},
+		{"0.0~git202001011200", []string{"1.0.0-1"}, true},
+		{"0.0.0-20200101120000-abcdef123456", []string{"0.0~git202001011200-1"}, true},
+		{"0.0.0-20200101120000-abcdef123456", []string{"0.0~git201912312359-1"}, false},
+		{"1.0.0", []string{"0.0~git202001011200-1"}, false},
+	}
+
+	for _, test := range tests {
+		dep := pkg.Dependency{Name: "github.com-foo-bar-src", Version: test.version}
+		if got := isSatisfied(dep, test.versions); got != test.expected {
+			t.Errorf("isSatisfied(%q, %v) = %v, expected %v", test.version, test.versions, got, test.expected)
+		}
+	}
+}
@@ -1,102 +1,192 @@
 package make
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/go-pkg-org/gopkg/internal/archive"
 	"github.com/go-pkg-org/gopkg/internal/config"
 	"github.com/go-pkg-org/gopkg/internal/pkg"
 	"github.com/go-pkg-org/gopkg/internal/util"
 	"github.com/rs/zerolog/log"
-	"io/ioutil"
+	"io"
+	"net/http"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 )
 
-// Make create a brand new control package from given import path
-func Make(importPath string) error {
-	directory := pkg.GetName(importPath, false)
-
-	if _, err := os.Stat(directory); err == nil {
-		return fmt.Errorf("already existing package directory: %s", directory)
-	}
+// majorVersionRegex matches the major version suffix of the modules import paths
+var majorVersionRegex = regexp.MustCompile(`^v[0-9]+$`)
 
+// Make create a brand new control package from given import path
+// the archive is used to check which dependencies are already packaged
+// the HTTP client is used to resolve the upstream repository of vanity import paths
+func Make(importPath string, arcClient archive.Client, httpClient *http.Client) error {
 	conf, err := config.Default()
 	if err != nil {
 		return err
 	}
 
+	_, err = makePackage(importPath, conf.GetMaintainerEntry(), getIndex(arcClient), httpClient)
+	return err
+}
+
+// makePackage create the control package of importPath in its own directory
+// & returns the result of the sources analysis
+func makePackage(importPath, maintainer string, index *archive.Index, httpClient *http.Client) (detection, error) {
+	directory := pkg.GetName(importPath, false)
+
+	if _, err := os.Stat(directory); err == nil {
+		return detection{}, fmt.Errorf("already existing package directory: %s", directory)
+	}
+
 	// Fetch & extract upstream source code
-	version, err := getUpstreamSource(importPath, directory)
+	version, remote, err := getUpstreamSource(importPath, directory, httpClient)
 	if err != nil {
-		return err
+		return detection{}, err
 	}
 	// Remove any leading v since we doesn't want it in gopkg archive
 	cleanVersion := strings.TrimPrefix(version, "v")
 
+	d, err := detectPackages(importPath, directory, index)
+	if err != nil {
+		return detection{}, err
+	}
+
+	m := pkg.ControlMeta{
+		Maintainers:       []string{maintainer},
+		Packages:          []pkg.Meta{},
+		ImportPath:        importPath,
+		BuildDependencies: d.buildDepends(),
+		// record the resolved remote so upstream releases are watched on the cloned repository
+		Watch: &pkg.Watch{Remote: remote},
+	}
+	m.Packages = append(m.Packages, d.binPkgs...)
+
+	// Create the control directory
+	if err := pkg.CreateCtrlDirectory(directory, cleanVersion, maintainer, m); err != nil {
+		return detection{}, err
+	}
+
+	log.Info().
+		Str("import-path", importPath).
+		Str("version", cleanVersion).
+		Msg("Detected values")
+	for _, p := range m.Packages {
+		log.Info().Str("package", p.Alias).Msg("Built package")
+	}
+
+	return d, nil
+}
+
+// dependency is a build dependency with the import path of the module providing it
+type dependency struct {
+	pkg.Dependency
+	importPath string
+}
+
+// detection is the result of the sources analysis
+type detection struct {
+	// deps are the build dependencies
+	deps []dependency
+	// unpackaged & outdated are the dependencies not packaged (or not recent enough) on the archive
+	unpackaged, outdated []dependency
+	// binPkgs are the detected binary packages
+	binPkgs []pkg.Meta
+}
+
+// buildDepends returns the build dependencies as recorded in the metadata
+func (d detection) buildDepends() []string {
+	return formatDeps(d.deps)
+}
+
+// detectPackages detects the build dependencies & the binary packages of the sources located in directory
+// the archive index (may be nil) is used to check which dependencies are already packaged
+func detectPackages(importPath, directory string, index *archive.Index) (detection, error) {
 	// Get defined importPaths (dependencies)
 	deps, err := getImportPaths(directory)
 	if err != nil {
-		return err
+		return detection{}, err
 	}
 
 	// Get std dependencies (builtin)
 	stdDeps, err := getStdDeps()
 	if err != nil {
-		return err
+		return detection{}, err
 	}
 
 	// Then get its dependencies
-	missingDeps, err := getMissingDeps(deps, stdDeps, importPath)
+	var d detection
+	d.deps, err = getDependencies(deps, stdDeps, importPath, directory)
 	if err != nil {
-		return err
+		return detection{}, err
 	}
 
-	if len(missingDeps) > 0 {
-		log.Warn().Strs("dependencies", missingDeps).Msg("Dependencies that need to be packaged first")
+	// Only warn about the dependencies not (or not recent enough) on the archive
+	d.unpackaged, d.outdated = checkPackagedDeps(d.deps, index)
+	if len(d.unpackaged) > 0 {
+		log.Warn().Strs("dependencies", formatDeps(d.unpackaged)).Msg("Dependencies that need to be packaged first")
+	}
+	if len(d.outdated) > 0 {
+		log.Warn().Strs("dependencies", formatDeps(d.outdated)).Msg("Dependencies packaged with a too old version")
 	}
 
-	// Convert dependencies into package name
-	var buildDepends []string
-	for _, missingDep := range missingDeps {
-		buildDepends = append(buildDepends, pkg.GetName(missingDep, true))
+	// Search for binary packages
+	d.binPkgs, err = getBinaryPackages(importPath, directory)
+	if err != nil {
+		return detection{}, err
 	}
 
-	m := pkg.ControlMeta{
-		Maintainers:       []string{conf.GetMaintainerEntry()},
-		Packages:          []pkg.Meta{},
-		ImportPath:        importPath,
-		BuildDependencies: buildDepends,
+	return d, nil
+}
+
+func formatDeps(deps []dependency) []string {
+	var result []string
+	for _, dep := range deps {
+		result = append(result, dep.String())
 	}
 
-	// Search for binary packages
-	binPkgs, err := getBinaryPackages(importPath, directory)
-	if err != nil {
-		return err
+	return result
+}
+
+// getDependencies returns the build dependencies of the given imports
+// they are resolved using go.mod when available, to get the modules roots & required versions
+func getDependencies(deps, stdDeps []string, importPath, directory string) ([]dependency, error) {
+	if hasGoMod(directory) {
+		modules, err := getModules(directory)
+		if err != nil {
+			return nil, err
+		}
+
+		return getModuleDeps(deps, stdDeps, modules), nil
 	}
-	m.Packages = append(m.Packages, binPkgs...)
 
-	// Create the control directory
-	if err := pkg.CreateCtrlDirectory(directory, cleanVersion, conf.GetMaintainerEntry(), m); err != nil {
-		return err
+	// GOPATH project: guess the dependencies roots
+	missingDeps, err := getMissingDeps(deps, stdDeps, importPath)
+	if err != nil {
+		return nil, err
 	}
 
-	log.Info().
-		Str("import-path", importPath).
-		Str("version", cleanVersion).
-		Msg("Detected values")
-	for _, p := range m.Packages {
-		log.Info().Str("package", p.Alias).Msg("Built package")
+	var result []dependency
+	for _, missingDep := range missingDeps {
+		result = append(result, dependency{
+			Dependency: pkg.Dependency{Name: pkg.GetName(missingDep, true)},
+			importPath: missingDep,
+		})
 	}
 
-	return nil
+	return result, nil
 }
 
 // Get the package missing dependencies dependencies
 // - remove the 'std' dependencies (builtin)
 // - remove the dependencies that belongs to the project we want to package
-// todo remove already packaged deps
 func getMissingDeps(deps, stdDeps []string, importPath string) ([]string, error) {
 	// Get 'real' dependencies
 	// i.e exclude std dependencies
@@ -129,7 +219,7 @@ func getMissingDeps(deps, stdDeps []string, importPath string) ([]string, error)
 			rootDep = fmt.Sprintf("%s/%s/%s", parts[0], parts[1], parts[2])
 		}
 
-		// And make sure it's not already packaged
+		// And make sure it's not already added
 		if !util.Contains(realDeps, rootDep) {
 			realDeps = append(realDeps, rootDep)
 		}
@@ -169,67 +259,132 @@ func parseLines(b []byte) []string {
 	// sanitize output
 	output := strings.ReplaceAll(string(b), "'", "")
 
-	// remove trailing \n
-	output = strings.TrimSuffix(output, "\n")
+	// remove empty lines (f.e packages without imports)
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
 
-	return strings.Split(output, "\n")
+// goPackage is a package listed by go list -json
+type goPackage struct {
+	Dir  string
+	Name string
 }
 
-// getExecutables will lookup for executable in given directory and returns their corresponding package
+// getBinaryPackages will lookup for main packages in given directory and returns their corresponding package
+// the packages are listed by the go tool, so build constraints, tests & testdata are respected
 func getBinaryPackages(importPath, directory string) ([]pkg.Meta, error) {
+	absDirectory, err := filepath.Abs(directory)
+	if err != nil {
+		return nil, err
+	}
+
+	// -e: report the packages even if they (or their dependencies) have errors
+	cmd := exec.Command("go", "list", "-e", "-json", "./...")
+	cmd.Dir = directory
+	cmd.Stderr = os.Stderr
+
+	b, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	goPkgs, err := parsePackages(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
 	var pkgs []pkg.Meta
+	for _, goPkg := range goPkgs {
+		if goPkg.Name != "main" {
+			continue
+		}
 
-	if err := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+		rel, err := filepath.Rel(absDirectory, goPkg.Dir)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		rel = filepath.ToSlash(rel)
 
-		if info.IsDir() {
-			return nil
+		// go build needs a ./ prefix to not consider the directory as an import path
+		main := "."
+		if rel != "." {
+			main = "./" + rel
 		}
 
-		b, err := ioutil.ReadFile(path)
-		if err != nil {
-			return err
+		aliasName := path.Join(importPath, rel)
+		// the module import path is the alias of the source package
+		if rel == "." {
+			aliasName = path.Join(importPath, getBinName(importPath))
 		}
+		pkgs = append(pkgs, pkg.Meta{
+			Alias:       aliasName,
+			Description: "TODO",
+			Main:        main,
+			BinName:     getBinName(aliasName),
+			Targets:     getDefaultTargets(),
+		})
+		log.Trace().Str("dir", goPkg.Dir).Str("alias", aliasName).Msg("Found binary package")
+	}
+
+	return pkgs, nil
+}
 
-		if strings.Contains(string(b), "func main()") && strings.HasSuffix(path, ".go") {
-			fileName := strings.Replace(info.Name(), ".go", "", 1)
-			aliasName := filepath.Join(importPath, fileName)
-			pkgs = append(pkgs, pkg.Meta{
-				Alias:       aliasName,
-				Description: "TODO",
-				Main:        strings.TrimPrefix(path, directory+"/"),
-				BinName:     strings.ReplaceAll(aliasName, "/", "-"),
-				Targets:     getDefaultTargets(),
-			})
-			log.Trace().Str("file", path).Str("alias", aliasName).Msg("Found binary package")
+// parsePackages parse the go list -json output, i.e a stream of JSON objects
+func parsePackages(r io.Reader) ([]goPackage, error) {
+	var pkgs []goPackage
+
+	dec := json.NewDecoder(r)
+	for {
+		var p goPackage
+		if err := dec.Decode(&p); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
 		}
-		return nil
-	}); err != nil {
-		return nil, err
+		pkgs = append(pkgs, p)
 	}
 
 	return pkgs, nil
 }
 
+// getBinName returns the binary name of the main package with given import path
+// i.e the name of its directory, ignoring the major version suffix (f.e github.com/foo/bar/v2 -> bar)
+func getBinName(importPath string) string {
+	name := path.Base(importPath)
+	if majorVersionRegex.MatchString(name) && path.Dir(importPath) != "." {
+		name = path.Base(path.Dir(importPath))
+	}
+
+	return name
+}
+
 // getUpstreamSource fetch latest available upstream source
-// this method return path to upstream source, version, and error if any
-func getUpstreamSource(importPath, where string) (string, error) {
-	remote := fmt.Sprintf("https://%s.git", importPath)
+// this method return the upstream version, the resolved git remote, and error if any
+func getUpstreamSource(importPath, where string, httpClient *http.Client) (string, string, error) {
+	remote, err := GetRemote(importPath, httpClient)
+	if err != nil {
+		return "", "", err
+	}
 	log.Debug().Str("remote", remote).Msg("Found upstream remote")
 
 	// Clone repository
 	log.Debug().Str("remote", remote).Msg("Cloning remote")
 	cmd := exec.Command("git", "clone", remote, where)
 	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("error: git clone %s result (%s)", remote, err)
+		return "", "", fmt.Errorf("error: git clone %s result (%s)", remote, err)
 	}
 
 	// Get git repository latest version
-	version, isTag, err := getGitVersion(where)
+	version, isTag, err := getGitVersion(where, "HEAD")
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 	log.Debug().Str("version", version).Bool("tagged", isTag).Msg("Found upstream version")
 
@@ -239,24 +394,24 @@ func getUpstreamSource(importPath, where string) (string, error) {
 		cmd = exec.Command("git", "checkout", version)
 		cmd.Dir = where
 		if err := cmd.Run(); err != nil {
-			return "", err
+			return "", "", err
 		}
 	}
 
-	return version, nil
+	return version, remote, nil
 }
 
-// getGitVersion will attempt to auto-detect the latest stable/tagged release
+// getGitVersion will attempt to auto-detect the latest stable/tagged release reachable from rev
 // if upstream tag release: it will return the latest tag
-// if upstream doesn't tag release: it will create a special version for the latest (HEAD) commit
-func getGitVersion(gitDir string) (string, bool, error) {
+// if upstream doesn't tag release: it will create a special version for the rev commit
+func getGitVersion(gitDir, rev string) (string, bool, error) {
 	// Extract latest tag / version
-	cmd := exec.Command("git", "describe", "--tags", "--abbrev=0")
+	cmd := exec.Command("git", "describe", "--tags", "--abbrev=0", rev)
 	cmd.Dir = gitDir
 	b, err := cmd.Output()
 	if err != nil {
 		// There maybe no tag available, create a manual version using commit date
-		cmd = exec.Command("git", "--no-pager", "log", "-1", "--date=short", "--pretty=format:%cD")
+		cmd = exec.Command("git", "--no-pager", "log", "-1", "--date=short", "--pretty=format:%cD", rev)
 		cmd.Dir = gitDir
 
 		b, err = cmd.Output()
@@ -270,12 +425,18 @@ func getGitVersion(gitDir string) (string, bool, error) {
 			return "", false, err
 		}
 
-		return fmt.Sprintf("0.0~git%d%d%d%d%d", date.Year(), date.Month(), date.Day(), date.Hour(), date.Minute()), false, nil
+		return gitVersion(date.UTC().Format("200601021504")), false, nil
 	}
 
 	return strings.TrimSuffix(string(b), "\n"), true, nil
 }
 
+// gitVersion returns the upstream version of untagged sources committed at timestamp (YYYYMMDDHHMM, UTC)
+// like the module pseudo-versions, it sorts before any tagged release
+func gitVersion(timestamp string) string {
+	return "0.0~git" + timestamp
+}
+
 func getDefaultTargets() map[string][]string {
 	return map[string][]string{
 		"linux":  {"amd64"},
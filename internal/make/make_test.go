@@ -34,7 +34,7 @@ func TestGetGitVersion(t *testing.T) {
 		t.Error(err)
 	}
 
-	v, isTag, err := getGitVersion(tmpDir)
+	v, isTag, err := getGitVersion(tmpDir, "HEAD")
 	if err != nil {
 		t.Error(err)
 	}
@@ -43,7 +43,7 @@ func TestGetGitVersion(t *testing.T) {
 		t.Error("Git version should not be a tag")
 	}
 
-	if v != "0.0~git20201015205" {
+	if v != "0.0~git202010151805" {
 		t.Errorf("Wrong git version (%s)", v)
 	}
 
@@ -52,7 +52,7 @@ func TestGetGitVersion(t *testing.T) {
 		t.Error(err)
 	}
 
-	v, isTag, err = getGitVersion(tmpDir)
+	v, isTag, err = getGitVersion(tmpDir, "HEAD")
 	if err != nil {
 		t.Error(err)
 	}
@@ -113,3 +113,51 @@ func runGitCmd(dir string, env []string, args ...string) error {
 
 	return nil
 }
+
+func TestGetBinaryPackages(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "gopkg_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	files := map[string]string{
+		"go.mod":  "module github.com/foo/bar/v2\n\ngo 1.14\n",
+		"main.go": "package main\n\nfunc main() {}\n",
+		// multiple files main package
+		"cmd/tool/main.go": "package main\n\nfunc main() { run() }\n",
+		"cmd/tool/run.go":  "package main\n\nfunc run() {}\n",
+		// library mentioning func main() in a comment
+		"lib/lib.go": "package lib\n\n// Usage: call Run from func main()\nfunc Run() {}\n",
+		// test files & fixtures
+		"lib/lib_test.go":          "package lib_test\n\nfunc main() {}\n",
+		"testdata/fixture/main.go": "package main\n\nfunc main() {}\n",
+		// excluded by build constraints
+		"tools/gen.go": "// +build ignore\n\npackage main\n\nfunc main() {}\n",
+		"README.md":    "Run it from func main()\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pkgs, err := getBinaryPackages("github.com/foo/bar/v2", tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pkgs) != 2 {
+		t.Fatalf("wrong binary packages: %v", pkgs)
+	}
+	if p := pkgs[0]; p.Alias != "github.com/foo/bar/v2/bar" || p.Main != "." || p.BinName != "bar" {
+		t.Errorf("wrong binary package: %+v", p)
+	}
+	if p := pkgs[1]; p.Alias != "github.com/foo/bar/v2/cmd/tool" || p.Main != "./cmd/tool" || p.BinName != "tool" {
+		t.Errorf("wrong binary package: %+v", p)
+	}
+}
@@ -0,0 +1,137 @@
+package make
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+	"github.com/go-pkg-org/gopkg/internal/util"
+	"github.com/rs/zerolog/log"
+)
+
+// pseudoVersionRegex matches the module pseudo-versions (vX.0.0-yyyymmddhhmmss-abcdefabcdef,
+// vX.Y.Z-pre.0.yyyymmddhhmmss-abcdefabcdef & vX.Y.Z-0.yyyymmddhhmmss-abcdefabcdef)
+// and captures the commit timestamp
+var pseudoVersionRegex = regexp.MustCompile(`^v?[0-9]+\.[0-9]+\.[0-9]+-(?:[0-9A-Za-z.-]*\.)?([0-9]{14})-[0-9a-f]{12}$`)
+
+// module is a module listed by go list -m -json
+type module struct {
+	Path    string
+	Version string
+	Main    bool
+	Replace *module
+}
+
+// getModules returns the modules required by the module located at directory
+func getModules(directory string) ([]module, error) {
+	cmd := exec.Command("go", "list", "-m", "-json", "all")
+	cmd.Dir = directory
+	cmd.Stderr = os.Stderr
+
+	b, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseModules(bytes.NewReader(b))
+}
+
+// parseModules parse the go list -m -json output, i.e a stream of JSON objects
+func parseModules(r io.Reader) ([]module, error) {
+	var modules []module
+
+	dec := json.NewDecoder(r)
+	for {
+		var m module
+		if err := dec.Decode(&m); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		modules = append(modules, m)
+	}
+
+	return modules, nil
+}
+
+// getModuleDeps returns the build dependencies of the given imports
+// i.e the modules providing them, with the version required by go.mod
+func getModuleDeps(imports, stdDeps []string, modules []module) []dependency {
+	deps := map[string]dependency{}
+	for _, imp := range imports {
+		if util.Contains(stdDeps, imp) {
+			continue
+		}
+
+		m, found := findModule(imp, modules)
+		if !found || m.Main {
+			continue
+		}
+
+		// the packaged module is the required one, not its replacement (local directory or fork)
+		if m.Replace != nil {
+			log.Warn().Str("module", m.Path).Str("replacement", m.Replace.Path).Msg("Ignoring replace directive")
+		}
+
+		deps[m.Path] = dependency{
+			Dependency: pkg.Dependency{Name: pkg.GetName(m.Path, true), Version: cleanModuleVersion(m.Version)},
+			importPath: m.Path,
+		}
+	}
+
+	var result []dependency
+	for _, dep := range deps {
+		result = append(result, dep)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// findModule returns the module providing the given import path, i.e the one with the longest matching path
+func findModule(importPath string, modules []module) (module, bool) {
+	var result module
+	found := false
+	for _, m := range modules {
+		if importPath != m.Path && !strings.HasPrefix(importPath, m.Path+"/") {
+			continue
+		}
+
+		if !found || len(m.Path) > len(result.Path) {
+			result = m
+			found = true
+		}
+	}
+
+	return result, found
+}
+
+// cleanModuleVersion converts a module version into an upstream version
+// Remove any leading v since we doesn't want it in gopkg archive
+// pseudo-versions are converted into the 0.0~gitYYYYMMDDHHMM scheme used for untagged sources
+func cleanModuleVersion(version string) string {
+	version = strings.TrimSuffix(version, "+incompatible")
+
+	if match := pseudoVersionRegex.FindStringSubmatch(version); match != nil {
+		return gitVersion(match[1][:12])
+	}
+
+	return strings.TrimPrefix(version, "v")
+}
+
+// hasGoMod returns true if the sources located at directory are a go module
+func hasGoMod(directory string) bool {
+	_, err := os.Stat(filepath.Join(directory, "go.mod"))
+	return err == nil
+}
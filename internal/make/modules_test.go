@@ -0,0 +1,90 @@
+package make
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+)
+
+const goListOutput = `{
+	"Path": "github.com/creekorful/mvnparser",
+	"Main": true,
+	"Dir": "/src/mvnparser",
+	"GoMod": "/src/mvnparser/go.mod"
+}
+{
+	"Path": "github.com/jedib0t/go-pretty/v6",
+	"Version": "v6.0.5"
+}
+{
+	"Path": "golang.org/x/crypto",
+	"Version": "v0.0.0-20190308221718-c2843e01d9a2"
+}
+{
+	"Path": "gopkg.in/yaml.v2",
+	"Version": "v2.3.0"
+}
+{
+	"Path": "github.com/foo/bar",
+	"Version": "v2.0.0+incompatible"
+}
+{
+	"Path": "github.com/foo/bar/baz",
+	"Version": "v1.1.0",
+	"Replace": {
+		"Path": "../baz"
+	}
+}
+{
+	"Path": "github.com/unused/dep",
+	"Version": "v1.0.0"
+}
+`
+
+func TestGetModuleDeps(t *testing.T) {
+	modules, err := parseModules(strings.NewReader(goListOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(modules) != 7 {
+		t.Fatalf("wrong number of modules: %d", len(modules))
+	}
+
+	imports := []string{"fmt", "os", "github.com/jedib0t/go-pretty/v6/table", "github.com/jedib0t/go-pretty/v6/text",
+		"golang.org/x/crypto/openpgp", "gopkg.in/yaml.v2", "github.com/foo/bar/qux", "github.com/foo/bar/baz/quux",
+		"github.com/creekorful/mvnparser/utils"}
+
+	deps := getModuleDeps(imports, []string{"fmt", "os"}, modules)
+	expected := []dependency{
+		{pkg.Dependency{Name: "github.com-foo-bar-baz-src", Version: "1.1.0"}, "github.com/foo/bar/baz"},
+		{pkg.Dependency{Name: "github.com-foo-bar-src", Version: "2.0.0"}, "github.com/foo/bar"},
+		{pkg.Dependency{Name: "github.com-jedib0t-go-pretty-v6-src", Version: "6.0.5"}, "github.com/jedib0t/go-pretty/v6"},
+		{pkg.Dependency{Name: "golang.org-x-crypto-src", Version: "0.0~git201903082217"},
+			"golang.org/x/crypto"},
+		{pkg.Dependency{Name: "gopkg.in-yaml.v2-src", Version: "2.3.0"}, "gopkg.in/yaml.v2"},
+	}
+	if !reflect.DeepEqual(deps, expected) {
+		t.Errorf("wrong dependencies: %v", deps)
+	}
+}
+
+func TestCleanModuleVersion(t *testing.T) {
+	tests := map[string]string{
+		"v1.2.3":                                          "1.2.3",
+		"v1.2.3-rc.1":                                     "1.2.3-rc.1",
+		"v2.0.0+incompatible":                             "2.0.0",
+		"v0.0.0-20200101120000-abcdef123456":              "0.0~git202001011200",
+		"v1.2.4-0.20200101120000-abcdef123456":            "0.0~git202001011200",
+		"v1.3.0-pre.0.20200101120000-abcdef123456":        "0.0~git202001011200",
+		"v2.0.0-20200101120000-abcdef123456+incompatible": "0.0~git202001011200",
+		"": "",
+	}
+
+	for version, expected := range tests {
+		if got := cleanModuleVersion(version); got != expected {
+			t.Errorf("cleanModuleVersion(%q) = %q, expected %q", version, got, expected)
+		}
+	}
+}
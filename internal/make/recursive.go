@@ -0,0 +1,200 @@
+package make
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/go-pkg-org/gopkg/internal/archive"
+	"github.com/go-pkg-org/gopkg/internal/config"
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+	"github.com/rs/zerolog/log"
+)
+
+// packager creates (or reuse) the control package of importPath
+// it returns the sources analysis & whether the control directory has been created
+type packager func(importPath string) (detection, bool, error)
+
+// step is a package of the packaging plan
+type step struct {
+	importPath string
+	directory  string
+	// created is false if the control directory already existed
+	created bool
+	// outdated are the dependencies packaged on the archive with a too old version
+	outdated []dependency
+}
+
+// plan is the result of a recursive packaging
+type plan struct {
+	// steps are the packages to build & upload, in dependency order
+	steps []step
+	// cycles are the dependency cycles found, the edge closing the cycle being ignored
+	cycles [][]string
+	// failures are the dependencies which could not be packaged
+	failures []failure
+}
+
+// failure is a dependency which could not be packaged
+type failure struct {
+	importPath string
+	// requiredBy is the package depending on it
+	requiredBy string
+	err        error
+}
+
+// visit states of the packages
+const (
+	visiting = iota + 1
+	visited
+)
+
+// planner walks the dependency graph in depth first order
+type planner struct {
+	packager packager
+	plan     plan
+	states   map[string]int
+	stack    []string
+}
+
+// MakeRecursive create the control packages of importPath & of its dependencies not packaged on the archive
+// a summary of the packages to build & upload, in dependency order, is written to w
+// along with the dependencies which could not be packaged
+func MakeRecursive(importPath string, arcClient archive.Client, httpClient *http.Client, w io.Writer) error {
+	conf, err := config.Default()
+	if err != nil {
+		return err
+	}
+
+	maintainer := conf.GetMaintainerEntry()
+	index := getIndex(arcClient)
+
+	p, err := makeRecursive(importPath, func(importPath string) (detection, bool, error) {
+		directory := pkg.GetName(importPath, false)
+
+		// reuse the control directory created by a previous run
+		if _, err := os.Stat(directory); err == nil {
+			log.Info().Str("directory", directory).Msg("Using existing package directory")
+			d, err := detectPackages(importPath, directory, index)
+			return d, false, err
+		}
+
+		d, err := makePackage(importPath, maintainer, index, httpClient)
+		return d, true, err
+	})
+	if err != nil {
+		return err
+	}
+
+	writePlan(w, p)
+	return nil
+}
+
+// makeRecursive packages importPath & its unpackaged dependencies using packager
+// only the failure to package importPath itself is returned, the other ones are reported in the plan
+func makeRecursive(importPath string, packager packager) (plan, error) {
+	p := planner{packager: packager, states: map[string]int{}}
+	if err := p.visit(importPath); err != nil {
+		return plan{}, err
+	}
+
+	return p.plan, nil
+}
+
+func (p *planner) visit(importPath string) error {
+	switch p.states[importPath] {
+	case visited:
+		return nil
+	case visiting:
+		// importPath is already on the stack: this is a cycle
+		var cycle []string
+		for i, current := range p.stack {
+			if current == importPath {
+				cycle = append(append(cycle, p.stack[i:]...), importPath)
+				break
+			}
+		}
+		log.Warn().Str("cycle", strings.Join(cycle, " -> ")).Msg("Found dependency cycle")
+		p.plan.cycles = append(p.plan.cycles, cycle)
+		return nil
+	}
+
+	p.states[importPath] = visiting
+	p.stack = append(p.stack, importPath)
+
+	d, created, err := p.packager(importPath)
+	if err != nil {
+		err = fmt.Errorf("error while packaging %s: %w", importPath, err)
+		if len(p.stack) == 1 {
+			return err
+		}
+
+		// keep packaging the other dependencies
+		requiredBy := p.stack[len(p.stack)-2]
+		log.Warn().Err(err).Str("requiredBy", requiredBy).Msg("Unable to package dependency")
+		p.plan.failures = append(p.plan.failures, failure{importPath: importPath, requiredBy: requiredBy, err: err})
+
+		p.stack = p.stack[:len(p.stack)-1]
+		p.states[importPath] = visited
+		return nil
+	}
+
+	for _, dep := range d.unpackaged {
+		if err := p.visit(dep.importPath); err != nil {
+			return err
+		}
+	}
+
+	p.stack = p.stack[:len(p.stack)-1]
+	p.states[importPath] = visited
+
+	// dependencies are visited first: the steps are in dependency order
+	p.plan.steps = append(p.plan.steps, step{
+		importPath: importPath,
+		directory:  pkg.GetName(importPath, false),
+		created:    created,
+		outdated:   d.outdated,
+	})
+
+	return nil
+}
+
+// writePlan writes the packaging plan summary to w
+func writePlan(w io.Writer, p plan) {
+	fmt.Fprintln(w, "Packages to build & upload (in this order):")
+	for i, s := range p.steps {
+		status := "created"
+		if !s.created {
+			status = "existing"
+		}
+		fmt.Fprintf(w, "  %d. %s (%s, %s)\n", i+1, s.importPath, s.directory, status)
+	}
+
+	var outdated []string
+	for _, s := range p.steps {
+		for _, dep := range s.outdated {
+			outdated = append(outdated, fmt.Sprintf("  %s required by %s", dep, s.importPath))
+		}
+	}
+	if len(outdated) > 0 {
+		fmt.Fprintln(w, "Packages to update to a newer upstream version:")
+		fmt.Fprintln(w, strings.Join(outdated, "\n"))
+	}
+
+	if len(p.failures) > 0 {
+		fmt.Fprintln(w, "Packages which could not be made (to package manually):")
+		for _, f := range p.failures {
+			fmt.Fprintf(w, "  %s required by %s: %s\n", f.importPath, f.requiredBy, f.err)
+		}
+	}
+
+	if len(p.cycles) > 0 {
+		fmt.Fprintln(w, "Dependency cycles:")
+		for _, cycle := range p.cycles {
+			fmt.Fprintf(w, "  %s (%s is built without %s)\n", strings.Join(cycle, " -> "),
+				cycle[len(cycle)-2], cycle[len(cycle)-1])
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package make
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+)
+
+func TestMakeRecursive(t *testing.T) {
+	// a depends on b & c, b depends on c & d, d depends on a (cycle)
+	graph := map[string][]string{
+		"github.com/foo/a": {"github.com/foo/b", "github.com/foo/c"},
+		"github.com/foo/b": {"github.com/foo/c", "github.com/foo/d"},
+		"github.com/foo/c": {},
+		"github.com/foo/d": {"github.com/foo/a"},
+	}
+
+	var made []string
+	p, err := makeRecursive("github.com/foo/a", func(importPath string) (detection, bool, error) {
+		made = append(made, importPath)
+
+		var d detection
+		for _, dep := range graph[importPath] {
+			d.unpackaged = append(d.unpackaged, dependency{
+				Dependency: pkg.Dependency{Name: pkg.GetName(dep, true)},
+				importPath: dep,
+			})
+		}
+		if importPath == "github.com/foo/c" {
+			d.outdated = []dependency{{Dependency: pkg.Dependency{Name: "github.com-foo-e-src", Version: "1.2.0"}}}
+		}
+
+		return d, importPath != "github.com/foo/c", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// each package is made once
+	if expected := []string{"github.com/foo/a", "github.com/foo/b", "github.com/foo/c", "github.com/foo/d"}; !reflect.DeepEqual(made, expected) {
+		t.Errorf("wrong made packages: %v", made)
+	}
+
+	var order []string
+	for _, s := range p.steps {
+		order = append(order, s.importPath)
+	}
+	if expected := []string{"github.com/foo/c", "github.com/foo/d", "github.com/foo/b", "github.com/foo/a"}; !reflect.DeepEqual(order, expected) {
+		t.Errorf("wrong order: %v", order)
+	}
+
+	if expected := [][]string{{"github.com/foo/a", "github.com/foo/b", "github.com/foo/d", "github.com/foo/a"}}; !reflect.DeepEqual(p.cycles, expected) {
+		t.Errorf("wrong cycles: %v", p.cycles)
+	}
+
+	var b bytes.Buffer
+	writePlan(&b, p)
+	expected := `Packages to build & upload (in this order):
+  1. github.com/foo/c (github.com-foo-c, existing)
+  2. github.com/foo/d (github.com-foo-d, created)
+  3. github.com/foo/b (github.com-foo-b, created)
+  4. github.com/foo/a (github.com-foo-a, created)
+Packages to update to a newer upstream version:
+  github.com-foo-e-src (>= 1.2.0) required by github.com/foo/c
+Dependency cycles:
+  github.com/foo/a -> github.com/foo/b -> github.com/foo/d -> github.com/foo/a (github.com/foo/d is built without github.com/foo/a)
+`
+	if b.String() != expected {
+		t.Errorf("wrong plan:\n%s", b.String())
+	}
+}
+
+func TestMakeRecursive_Error(t *testing.T) {
+	errFailed := errors.New("clone failed")
+
+	// the package itself cannot be made
+	_, err := makeRecursive("github.com/foo/a", func(importPath string) (detection, bool, error) {
+		return detection{}, false, errFailed
+	})
+	if !errors.Is(err, errFailed) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// a dependency cannot be made: the other ones are still made
+	var made []string
+	p, err := makeRecursive("github.com/foo/a", func(importPath string) (detection, bool, error) {
+		made = append(made, importPath)
+		switch importPath {
+		case "github.com/foo/a":
+			return detection{unpackaged: []dependency{
+				{Dependency: pkg.Dependency{Name: "github.com-foo-b-v2-src"}, importPath: "github.com/foo/b/v2"},
+				{Dependency: pkg.Dependency{Name: "github.com-foo-c-src"}, importPath: "github.com/foo/c"},
+			}}, true, nil
+		case "github.com/foo/b/v2":
+			return detection{}, false, errFailed
+		default:
+			return detection{}, true, nil
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{"github.com/foo/a", "github.com/foo/b/v2", "github.com/foo/c"}; !reflect.DeepEqual(made, expected) {
+		t.Errorf("wrong made packages: %v", made)
+	}
+	if len(p.steps) != 2 || p.steps[0].importPath != "github.com/foo/c" || p.steps[1].importPath != "github.com/foo/a" {
+		t.Errorf("wrong steps: %v", p.steps)
+	}
+	if len(p.failures) != 1 || p.failures[0].importPath != "github.com/foo/b/v2" ||
+		p.failures[0].requiredBy != "github.com/foo/a" || !errors.Is(p.failures[0].err, errFailed) {
+		t.Errorf("wrong failures: %v", p.failures)
+	}
+
+	var b bytes.Buffer
+	writePlan(&b, p)
+	expected := `Packages to build & upload (in this order):
+  1. github.com/foo/c (github.com-foo-c, created)
+  2. github.com/foo/a (github.com-foo-a, created)
+Packages which could not be made (to package manually):
+  github.com/foo/b/v2 required by github.com/foo/a: error while packaging github.com/foo/b/v2: clone failed
+`
+	if b.String() != expected {
+		t.Errorf("wrong plan:\n%s", b.String())
+	}
+}
@@ -0,0 +1,82 @@
+package make
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-pkg-org/gopkg/internal/util"
+)
+
+// knownHosts are the code hosting sites whose repositories are located at host/owner/repo
+var knownHosts = []string{"github.com", "gitlab.com", "bitbucket.org"}
+
+var (
+	metaTagRegex = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	nameRegex    = regexp.MustCompile(`(?is)\sname\s*=\s*["']go-import["']`)
+	contentRegex = regexp.MustCompile(`(?is)\scontent\s*=\s*["']([^"']*)["']`)
+)
+
+// GetRemote returns the git remote of the repository providing the module importPath
+// known hosts are resolved directly (dropping any sub directory or major version suffix),
+// the other import paths using their go-import meta tag
+func GetRemote(importPath string, httpClient *http.Client) (string, error) {
+	parts := strings.Split(importPath, "/")
+	if util.Contains(knownHosts, parts[0]) {
+		if len(parts) < 3 {
+			return "", fmt.Errorf("invalid import path %s", importPath)
+		}
+		return fmt.Sprintf("https://%s.git", strings.Join(parts[:3], "/")), nil
+	}
+
+	url := fmt.Sprintf("https://%s?go-get=1", importPath)
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error while resolving %s: %s returned %s", importPath, url, resp.Status)
+	}
+
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+
+	return parseGoImport(importPath, b)
+}
+
+// parseGoImport returns the git repository of importPath declared by the go-import meta tags of page
+// i.e <meta name="go-import" content="prefix vcs repo">
+func parseGoImport(importPath string, page []byte) (string, error) {
+	for _, tag := range metaTagRegex.FindAll(page, -1) {
+		if !nameRegex.Match(tag) {
+			continue
+		}
+		match := contentRegex.FindSubmatch(tag)
+		if match == nil {
+			continue
+		}
+
+		fields := strings.Fields(string(match[1]))
+		if len(fields) != 3 {
+			continue
+		}
+
+		prefix, vcs, repo := fields[0], fields[1], fields[2]
+		if importPath != prefix && !strings.HasPrefix(importPath, prefix+"/") {
+			continue
+		}
+		// other entries may point to a module proxy
+		if vcs == "git" {
+			return repo, nil
+		}
+	}
+
+	return "", fmt.Errorf("no git repository declared for %s", importPath)
+}
@@ -0,0 +1,61 @@
+package make
+
+import (
+	"testing"
+)
+
+func TestGetRemote_KnownHosts(t *testing.T) {
+	tests := map[string]string{
+		"github.com/foo/bar":        "https://github.com/foo/bar.git",
+		"github.com/foo/bar/v2":     "https://github.com/foo/bar.git",
+		"github.com/foo/bar/baz/v3": "https://github.com/foo/bar.git",
+		"gitlab.com/foo/bar":        "https://gitlab.com/foo/bar.git",
+	}
+
+	for importPath, expected := range tests {
+		remote, err := GetRemote(importPath, nil)
+		if err != nil {
+			t.Errorf("GetRemote(%s): %s", importPath, err)
+			continue
+		}
+		if remote != expected {
+			t.Errorf("GetRemote(%s) = %s, expected %s", importPath, remote, expected)
+		}
+	}
+
+	if _, err := GetRemote("github.com/foo", nil); err == nil {
+		t.Error("GetRemote should have failed")
+	}
+}
+
+func TestParseGoImport(t *testing.T) {
+	page := []byte(`<html>
+<head>
+<meta name="go-import" content="golang.org/x/crypto mod https://proxy.example.com">
+<meta content="golang.org/x/crypto git https://go.googlesource.com/crypto" name="go-import"/>
+<meta name="go-import" content="gopkg.in/yaml.v2 git https://gopkg.in/yaml.v2">
+<meta name="go-source" content="golang.org/x/crypto https://github.com/golang/crypto/ x y">
+</head>
+</html>`)
+
+	tests := map[string]string{
+		"golang.org/x/crypto":         "https://go.googlesource.com/crypto",
+		"golang.org/x/crypto/openpgp": "https://go.googlesource.com/crypto",
+		"gopkg.in/yaml.v2":            "https://gopkg.in/yaml.v2",
+	}
+
+	for importPath, expected := range tests {
+		repo, err := parseGoImport(importPath, page)
+		if err != nil {
+			t.Errorf("parseGoImport(%s): %s", importPath, err)
+			continue
+		}
+		if repo != expected {
+			t.Errorf("parseGoImport(%s) = %s, expected %s", importPath, repo, expected)
+		}
+	}
+
+	if _, err := parseGoImport("golang.org/x/cryptography", page); err == nil {
+		t.Error("parseGoImport should have failed")
+	}
+}
@@ -0,0 +1,223 @@
+package make
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-pkg-org/gopkg/internal/archive"
+	"github.com/go-pkg-org/gopkg/internal/changelog"
+	"github.com/go-pkg-org/gopkg/internal/config"
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+	"github.com/rs/zerolog/log"
+)
+
+// upstreamRev is the upstream default branch once fetched
+const upstreamRev = "origin/HEAD"
+
+// Update refresh the control directory located at directory to the latest upstream version
+// the detected dependencies & binary packages are merged into the existing metadata
+// the archive is used to check which dependencies are already packaged
+func Update(directory string, arcClient archive.Client) error {
+	conf, err := config.Default()
+	if err != nil {
+		return err
+	}
+
+	version, err := update(directory, conf.GetMaintainerEntry(), arcClient)
+	if err != nil {
+		return err
+	}
+
+	if version != "" {
+		log.Info().Str("version", version).Msg("Successfully updated package")
+	}
+
+	return nil
+}
+
+// update refresh the control directory & returns the new release version
+// an empty version is returned if the package is already up to date
+func update(directory, uploader string, arcClient archive.Client) (string, error) {
+	m, c, err := pkg.ReadCtrlDirectory(directory)
+	if err != nil {
+		return "", err
+	}
+
+	lastRelease, err := c.LastRelease()
+	if err != nil {
+		return "", err
+	}
+
+	// Fetch the upstream changes
+	version, isTag, err := fetchUpstreamSource(directory)
+	if err != nil {
+		return "", err
+	}
+	// Remove any leading v since we doesn't want it in gopkg archive
+	cleanVersion := strings.TrimPrefix(version, "v")
+
+	if err := pkg.ValidateVersion(cleanVersion + "-1"); err != nil {
+		return "", err
+	}
+	if pkg.CompareVersions(cleanVersion+"-1", lastRelease.Version) <= 0 {
+		log.Info().Str("version", lastRelease.Version).Msg("Package is already up to date")
+		return "", nil
+	}
+
+	// Align the source code with the new version
+	rev := upstreamRev
+	if isTag {
+		rev = version
+	}
+	log.Debug().Str("rev", rev).Msg("Checking out upstream version")
+	if err := runGit(directory, "checkout", "-q", rev); err != nil {
+		return "", err
+	}
+
+	d, err := detectPackages(m.ImportPath, directory, getIndex(arcClient))
+	if err != nil {
+		return "", err
+	}
+
+	m = mergeMetadata(m, d.buildDepends(), d.binPkgs, directory)
+	if err := pkg.WriteCtrlMetadata(directory, m); err != nil {
+		return "", err
+	}
+
+	return changelog.NewUpstream(directory, cleanVersion, uploader, nil)
+}
+
+// fetchUpstreamSource fetch the upstream changes into the git repository located at directory
+// and returns the latest upstream version
+func fetchUpstreamSource(directory string) (string, bool, error) {
+	if _, err := os.Stat(filepath.Join(directory, ".git")); err != nil {
+		return "", false, fmt.Errorf("%s is not a git repository", directory)
+	}
+
+	// Changes to the tracked files (f.e applied patches) would prevent checking out the new version
+	cmd := exec.Command("git", "status", "--porcelain", "--untracked-files=no")
+	cmd.Dir = directory
+	b, err := cmd.Output()
+	if err != nil {
+		return "", false, err
+	}
+	if len(strings.TrimSpace(string(b))) > 0 {
+		return "", false, fmt.Errorf("%s has local changes, revert them before updating", directory)
+	}
+
+	log.Debug().Str("directory", directory).Msg("Fetching upstream")
+	if err := runGit(directory, "fetch", "-q", "--tags", "origin"); err != nil {
+		return "", false, err
+	}
+	// make sure origin/HEAD exist even if the repository has not been cloned
+	if err := runGit(directory, "remote", "set-head", "origin", "--auto"); err != nil {
+		return "", false, err
+	}
+
+	version, isTag, err := getGitVersion(directory, upstreamRev)
+	if err != nil {
+		return "", false, err
+	}
+	log.Debug().Str("version", version).Bool("tagged", isTag).Msg("Found upstream version")
+
+	return version, isTag, nil
+}
+
+// mergeMetadata merges the detected build dependencies & binary packages into the existing metadata
+// the existing packages are kept as is (they may have been edited by hand) as long as their main file exist
+func mergeMetadata(m pkg.ControlMeta, buildDepends []string, binPkgs []pkg.Meta, directory string) pkg.ControlMeta {
+	for _, dep := range buildDepends {
+		m.BuildDependencies = mergeDependency(m.BuildDependencies, dep)
+	}
+
+	detected := map[string]pkg.Meta{}
+	for _, p := range binPkgs {
+		detected[p.Alias] = p
+	}
+
+	// the packages are matched using their alias or main file since both may have been edited
+	packages := []pkg.Meta{}
+	knownAliases, knownMains := map[string]bool{}, map[string]bool{}
+	for _, p := range m.Packages {
+		knownAliases[p.Alias] = true
+
+		if _, err := os.Stat(filepath.Join(directory, p.Main)); err == nil {
+			knownMains[mainDir(p.Main)] = true
+			packages = append(packages, p)
+			continue
+		}
+
+		// the main file has been moved, or the binary removed
+		if d, exist := detected[p.Alias]; exist {
+			log.Info().Str("package", p.Alias).Str("main", d.Main).Msg("Updated package main file")
+			p.Main = d.Main
+			knownMains[mainDir(p.Main)] = true
+			packages = append(packages, p)
+		} else {
+			log.Warn().Str("package", p.Alias).Msg("Removed package (main file no longer exist)")
+		}
+	}
+
+	for _, p := range binPkgs {
+		if !knownAliases[p.Alias] && !knownMains[mainDir(p.Main)] {
+			log.Info().Str("package", p.Alias).Msg("Added package")
+			packages = append(packages, p)
+		}
+	}
+
+	m.Packages = packages
+	return m
+}
+
+// mergeDependency adds the detected dependency to the existing ones
+// if the dependency already exist its required version is raised if needed
+func mergeDependency(existing []string, detected string) []string {
+	newDep, err := pkg.ParseDependency(detected)
+	if err != nil {
+		return existing
+	}
+
+	for i, dep := range existing {
+		oldDep, err := pkg.ParseDependency(dep)
+		if err != nil || oldDep.Name != newDep.Name {
+			continue
+		}
+
+		if newDep.Version != "" && (oldDep.Version == "" ||
+			pkg.CompareVersions(newDep.Version+"-1", oldDep.Version+"-1") > 0) {
+			log.Info().Str("dependency", detected).Msg("Updated build dependency")
+			existing[i] = newDep.String()
+		}
+		return existing
+	}
+
+	log.Info().Str("dependency", detected).Msg("Added build dependency")
+	return append(existing, detected)
+}
+
+// mainDir returns the directory of the main package, main being either the package directory or one of its files
+func mainDir(main string) string {
+	main = filepath.Clean(main)
+	if strings.HasSuffix(main, ".go") {
+		return filepath.Dir(main)
+	}
+
+	return main
+}
+
+// runGit runs the given git command in directory
+func runGit(directory string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = directory
+	cmd.Stderr = os.Stderr
+
+	log.Trace().Msgf("Executing `%s`", cmd.String())
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("error: %s result (%s)", cmd.String(), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package make
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-pkg-org/gopkg/internal/archive"
+	"github.com/go-pkg-org/gopkg/internal/archive_mock"
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+	"github.com/golang/mock/gomock"
+)
+
+func TestUpdate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	arcClient := archive_mock.NewMockClient(ctrl)
+	arcClient.EXPECT().GetIndex().Return(archive.NewIndex(), nil).AnyTimes()
+
+	tmpDir, err := ioutil.TempDir("", "gopkg_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	upstreamDir := filepath.Join(tmpDir, "upstream")
+	directory := filepath.Join(tmpDir, "github.com-foo-bar")
+
+	writeFile(t, filepath.Join(upstreamDir, "go.mod"), "module github.com/foo/bar\n\ngo 1.14\n")
+	writeFile(t, filepath.Join(upstreamDir, "bar.go"), "package main\n\nfunc main() {}\n")
+	for _, args := range [][]string{{"init", "-q"}, {"add", "."}, {"commit", "-q", "-m", "initial"}, {"tag", "v1.0.0"},
+		{"clone", "-q", upstreamDir, directory}} {
+		if err := runGitCmd(tmpDir, nil, append([]string{"-C", upstreamDir}, args...)...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// control directory edited by hand
+	uploader := "John Doe <john@doe.com>"
+	if err := pkg.CreateCtrlDirectory(directory, "1.0.0", uploader, pkg.ControlMeta{
+		ImportPath:        "github.com/foo/bar",
+		Maintainers:       []string{uploader},
+		BuildDependencies: []string{"github.com-foo-baz-dev"},
+		Packages: []pkg.Meta{{Alias: "github.com/foo/bar", Main: "bar.go", BinName: "bar", Description: "Bar tool",
+			Targets: map[string][]string{"linux": {"arm64"}}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	version, err := update(directory, uploader, arcClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "" {
+		t.Errorf("package should be up to date (got %s)", version)
+	}
+
+	// new upstream version with a new binary
+	writeFile(t, filepath.Join(upstreamDir, "cmd", "qux", "qux.go"), "package main\n\nfunc main() {}\n")
+	for _, args := range [][]string{{"add", "."}, {"commit", "-q", "-m", "add qux"}, {"tag", "v1.1.0"}} {
+		if err := runGitCmd(upstreamDir, nil, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	version, err = update(directory, uploader, arcClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "1.1.0-1" {
+		t.Errorf("wrong version: %s", version)
+	}
+
+	m, c, err := pkg.ReadCtrlDirectory(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m.BuildDependencies) != 1 || m.BuildDependencies[0] != "github.com-foo-baz-dev" {
+		t.Errorf("wrong build dependencies: %v", m.BuildDependencies)
+	}
+	if len(m.Packages) != 2 {
+		t.Fatalf("wrong packages: %v", m.Packages)
+	}
+	if p := m.Packages[0]; p.Description != "Bar tool" || p.Targets["linux"][0] != "arm64" {
+		t.Errorf("hand edited package has been changed: %+v", p)
+	}
+	if p := m.Packages[1]; p.Main != "./cmd/qux" || p.Alias != "github.com/foo/bar/cmd/qux" || p.BinName != "qux" {
+		t.Errorf("wrong added package: %+v", p)
+	}
+
+	last, err := c.LastRelease()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.Version != "1.1.0-1" || last.Uploader != uploader {
+		t.Errorf("wrong release: %+v", last)
+	}
+}
+
+func TestMergeDependency(t *testing.T) {
+	deps := []string{"github.com-foo-bar-src (>= 1.2.0)", "github.com-foo-baz-src", "github.com-foo-qux-src (>= 2.0.0)"}
+
+	deps = mergeDependency(deps, "github.com-foo-bar-src (>= 1.3.0)")
+	deps = mergeDependency(deps, "github.com-foo-baz-src (>= 0.1.0)")
+	deps = mergeDependency(deps, "github.com-foo-qux-src (>= 1.0.0)")
+	deps = mergeDependency(deps, "github.com-foo-quux-src (>= 1.0.0)")
+
+	expected := []string{"github.com-foo-bar-src (>= 1.3.0)", "github.com-foo-baz-src (>= 0.1.0)",
+		"github.com-foo-qux-src (>= 2.0.0)", "github.com-foo-quux-src (>= 1.0.0)"}
+	if !reflect.DeepEqual(deps, expected) {
+		t.Errorf("wrong dependencies: %v", deps)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0640); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,259 @@
+package patch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrNoChanges is returned when there is no changes to record into a patch
+var ErrNoChanges = errors.New("no changes to record")
+
+// Apply applies the patches series of the control directory located at path
+// on the sources. Patches recorded as applied are skipped, the remaining ones
+// are checked before touching the sources so a failure leaves them untouched
+// the series is returned, since all its patches are applied afterward
+func Apply(path string) ([]string, error) {
+	series, err := pkg.ReadSeries(path)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := pkg.ReadApplied(path)
+	if err != nil {
+		return nil, err
+	}
+	for i, name := range applied {
+		if i >= len(series) || series[i] != name {
+			return nil, fmt.Errorf("applied patches (%s) do not match the series (%s): restore the pristine sources first",
+				strings.Join(applied, ", "), strings.Join(series, ", "))
+		}
+	}
+	if len(applied) > 0 {
+		log.Debug().Strs("patches", applied).Msg("Patches already applied")
+	}
+
+	remaining := series[len(applied):]
+	if len(remaining) == 0 {
+		return series, nil
+	}
+
+	if err := applyPatches(path, remaining, false); err != nil {
+		return nil, err
+	}
+	if err := pkg.WriteApplied(path, series); err != nil {
+		return nil, err
+	}
+
+	for _, name := range remaining {
+		log.Info().Str("patch", name).Msg("Applied patch")
+	}
+
+	return series, nil
+}
+
+// Revert reverts the patches recorded as applied on the sources of the control directory located at path
+// so the pristine sources are restored. The sources are left untouched if the patches cannot be reverted
+func Revert(path string) error {
+	applied, err := pkg.ReadApplied(path)
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		return nil
+	}
+
+	// git reverts the patches of a single input from the last one
+	if err := applyPatches(path, applied, true); err != nil {
+		return err
+	}
+	if err := pkg.WriteApplied(path, nil); err != nil {
+		return err
+	}
+
+	for i := len(applied) - 1; i >= 0; i-- {
+		log.Info().Str("patch", applied[i]).Msg("Reverted patch")
+	}
+
+	return nil
+}
+
+// applyPatches applies (or reverts) the given patches series on the sources of the control directory located at path
+// the patches are checked first so a failure leaves the sources untouched
+func applyPatches(path string, names []string, reverse bool) error {
+	// the patches are given as a single input, so each one is checked on top of the previous ones
+	var patches bytes.Buffer
+	for _, name := range names {
+		b, err := ioutil.ReadFile(pkg.PatchPath(path, name))
+		if err != nil {
+			return err
+		}
+		patches.Write(b)
+		if len(b) > 0 && b[len(b)-1] != '\n' {
+			patches.WriteByte('\n')
+		}
+	}
+
+	args := []string{"apply"}
+	action := "applying"
+	if reverse {
+		args = append(args, "--reverse")
+		action = "reverting"
+	}
+
+	if _, err := gitInput(path, bytes.NewReader(patches.Bytes()), append(args, "--check")...); err != nil {
+		return fmt.Errorf("error while checking patches %s: %w", strings.Join(names, ", "), err)
+	}
+	// git apply is atomic: either every patch is applied or none
+	if _, err := gitInput(path, bytes.NewReader(patches.Bytes()), args...); err != nil {
+		return fmt.Errorf("error while %s patches %s: %w", action, strings.Join(names, ", "), err)
+	}
+
+	return nil
+}
+
+// New creates a patch named name recording the changes of the working tree
+// not already covered by the patches series, and add it on top of the series
+func New(path, name string) error {
+	series, err := pkg.ReadSeries(path)
+	if err != nil {
+		return err
+	}
+
+	for _, existing := range series {
+		if existing == name {
+			return fmt.Errorf("patch %s already exist", name)
+		}
+	}
+	if _, err := os.Stat(pkg.PatchPath(path, name)); err == nil {
+		return fmt.Errorf("patch %s already exist", name)
+	}
+
+	if err := pkg.ValidatePatchName(name); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(pkg.PatchPath(path, name)), 0750); err != nil {
+		return err
+	}
+
+	series = append(series, name)
+	if err := writePatch(path, series); err != nil {
+		return err
+	}
+	if err := pkg.WriteSeries(path, series); err != nil {
+		return err
+	}
+
+	// the changes recorded into the patch are on the sources
+	return pkg.WriteApplied(path, series)
+}
+
+// Refresh updates the topmost patch of the series with the changes of the working tree
+// the refreshed patch name is returned
+func Refresh(path string) (string, error) {
+	series, err := pkg.ReadSeries(path)
+	if err != nil {
+		return "", err
+	}
+	if len(series) == 0 {
+		return "", errors.New("no patches to refresh")
+	}
+
+	if err := writePatch(path, series); err != nil {
+		return "", err
+	}
+	if err := pkg.WriteApplied(path, series); err != nil {
+		return "", err
+	}
+
+	return series[len(series)-1], nil
+}
+
+// writePatch writes the last patch of series, as the difference between
+// the working tree & the HEAD commit with the previous patches applied
+func writePatch(path string, series []string) error {
+	if _, err := os.Stat(filepath.Join(path, ".git")); err != nil {
+		return fmt.Errorf("%s is not a git repository", path)
+	}
+
+	// use a temporary index to not mess with the user one
+	tmpDir, err := ioutil.TempDir("", "gopkg-patch-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	env := []string{fmt.Sprintf("GIT_INDEX_FILE=%s", filepath.Join(tmpDir, "index"))}
+
+	if _, err := git(path, env, "read-tree", "HEAD"); err != nil {
+		return err
+	}
+	for _, name := range series[:len(series)-1] {
+		if _, err := git(path, env, "apply", "--cached", pkg.PatchPath(path, name)); err != nil {
+			return fmt.Errorf("error while applying patch %s: %w", name, err)
+		}
+	}
+
+	// the control directory is not part of the patched sources
+	diff, err := git(path, env, "diff", "--binary", "--", ".", ":(exclude)"+pkg.GoPkgDir)
+	if err != nil {
+		return err
+	}
+	if len(diff) == 0 {
+		return ErrNoChanges
+	}
+
+	name := series[len(series)-1]
+	if err := ioutil.WriteFile(pkg.PatchPath(path, name), diff, 0640); err != nil {
+		return err
+	}
+
+	log.Info().Str("patch", name).Msg("Wrote patch")
+	return nil
+}
+
+// git runs the given git command in dir and returns its output
+func git(dir string, env []string, args ...string) ([]byte, error) {
+	return run(dir, env, nil, args...)
+}
+
+// gitInput runs the given git command in dir, reading its input from stdin, and returns its output
+func gitInput(dir string, stdin io.Reader, args ...string) ([]byte, error) {
+	return run(dir, nil, stdin, args...)
+}
+
+func run(dir string, env []string, stdin io.Reader, args ...string) ([]byte, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = absDir
+	cmd.Stdin = stdin
+	cmd.Stderr = &stderr
+	// do not use the repository of a parent directory (f.e extracted control package)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("GIT_CEILING_DIRECTORIES=%s", filepath.Dir(absDir)))
+	cmd.Env = append(cmd.Env, env...)
+
+	log.Trace().Msgf("Executing `%s`", cmd.String())
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %s", err, msg)
+		}
+		return nil, err
+	}
+
+	return out, nil
+}
@@ -0,0 +1,190 @@
+package patch
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+)
+
+func TestPatches(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "gopkg_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	mainPath := filepath.Join(dir, "main.go")
+	writeFile(t, mainPath, "package main\n\nfunc main() {\n}\n")
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "main.go"},
+		{"-c", "user.name=John Doe", "-c", "user.email=john@doe.com", "commit", "-q", "-m", "initial commit"},
+	} {
+		if _, err := git(dir, nil, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := Refresh(dir); err == nil {
+		t.Error("Refresh should have failed")
+	}
+	if err := New(dir, "empty.patch"); !errors.Is(err, ErrNoChanges) {
+		t.Errorf("expected ErrNoChanges, got %v", err)
+	}
+
+	// first patch
+	writeFile(t, mainPath, "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n")
+	// the control directory is not patched
+	writeFile(t, filepath.Join(dir, pkg.GoPkgDir, "metadata.yaml"), "importpath: github.com/foo/bar\n")
+	if err := New(dir, "hello.patch"); err != nil {
+		t.Fatal(err)
+	}
+	if err := New(dir, "hello.patch"); err == nil {
+		t.Error("New should have failed")
+	}
+
+	// second patch only contains the new changes
+	writeFile(t, mainPath, "package main\n\nfunc main() {\n\tprintln(\"hello\")\n\tprintln(\"world\")\n}\n")
+	if err := New(dir, "world.patch"); err != nil {
+		t.Fatal(err)
+	}
+
+	patch := readFile(t, pkg.PatchPath(dir, "world.patch"))
+	if !strings.Contains(patch, "+\tprintln(\"world\")") || strings.Contains(patch, "+\tprintln(\"hello\")") {
+		t.Errorf("wrong patch content: %s", patch)
+	}
+	if strings.Contains(readFile(t, pkg.PatchPath(dir, "hello.patch")), pkg.GoPkgDir) {
+		t.Error("the control directory should not be patched")
+	}
+
+	// refresh the topmost patch
+	writeFile(t, mainPath, "package main\n\nfunc main() {\n\tprintln(\"hello\")\n\tprintln(\"world!\")\n}\n")
+	name, err := Refresh(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "world.patch" {
+		t.Errorf("wrong refreshed patch: %s", name)
+	}
+	if patch := readFile(t, pkg.PatchPath(dir, "world.patch")); !strings.Contains(patch, "+\tprintln(\"world!\")") {
+		t.Errorf("wrong patch content: %s", patch)
+	}
+
+	// the patches are recorded as applied
+	if applied, _ := pkg.ReadApplied(dir); !reflect.DeepEqual(applied, []string{"hello.patch", "world.patch"}) {
+		t.Errorf("wrong applied patches: %v", applied)
+	}
+
+	// apply on pristine sources
+	restore(t, dir)
+
+	series, err := Apply(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(series, []string{"hello.patch", "world.patch"}) {
+		t.Errorf("wrong series: %v", series)
+	}
+
+	expected := "package main\n\nfunc main() {\n\tprintln(\"hello\")\n\tprintln(\"world!\")\n}\n"
+	if content := readFile(t, mainPath); content != expected {
+		t.Errorf("wrong patched content: %s", content)
+	}
+
+	// already applied patches are skipped
+	if _, err := Apply(dir); err != nil {
+		t.Fatal(err)
+	}
+	if content := readFile(t, mainPath); content != expected {
+		t.Errorf("wrong patched content: %s", content)
+	}
+
+	// revert the applied patches
+	if err := Revert(dir); err != nil {
+		t.Fatal(err)
+	}
+	if content := readFile(t, mainPath); content != "package main\n\nfunc main() {\n}\n" {
+		t.Errorf("wrong reverted content: %s", content)
+	}
+	if applied, _ := pkg.ReadApplied(dir); len(applied) != 0 {
+		t.Errorf("no patches should be recorded as applied: %v", applied)
+	}
+
+	// partially applied series
+	restore(t, dir)
+	if _, err := git(dir, nil, "apply", pkg.PatchPath(dir, "hello.patch")); err != nil {
+		t.Fatal(err)
+	}
+	if err := pkg.WriteApplied(dir, []string{"hello.patch"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Apply(dir); err != nil {
+		t.Fatal(err)
+	}
+	if content := readFile(t, mainPath); content != expected {
+		t.Errorf("wrong patched content: %s", content)
+	}
+
+	// patches applied in another order
+	restore(t, dir)
+	if err := pkg.WriteApplied(dir, []string{"world.patch"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Apply(dir); err == nil {
+		t.Error("Apply should have failed")
+	}
+
+	// the series does not apply: the sources are left untouched
+	restore(t, dir)
+	writeFile(t, pkg.PatchPath(dir, "world.patch"), strings.ReplaceAll(readFile(t, pkg.PatchPath(dir, "world.patch")),
+		"println(\"hello\")", "println(\"bye\")"))
+	if _, err := Apply(dir); err == nil {
+		t.Error("Apply should have failed")
+	}
+	if content := readFile(t, mainPath); content != "package main\n\nfunc main() {\n}\n" {
+		t.Errorf("sources should not have been patched: %s", content)
+	}
+	if applied, _ := pkg.ReadApplied(dir); len(applied) != 0 {
+		t.Errorf("no patches should be recorded as applied: %v", applied)
+	}
+}
+
+// restore restores the pristine sources
+func restore(t *testing.T, dir string) {
+	if _, err := git(dir, nil, "checkout", "--", "main.go"); err != nil {
+		t.Fatal(err)
+	}
+	if err := pkg.WriteApplied(dir, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0640); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
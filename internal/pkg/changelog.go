@@ -1,11 +1,17 @@
 package pkg
 
 import (
+	"errors"
 	"fmt"
-	"gopkg.in/yaml.v2"
+	"github.com/go-pkg-org/gopkg/internal/util"
+	"gopkg.in/yaml.v3"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 )
 
 const changelogFile = "changelog.yaml"
@@ -24,13 +30,241 @@ type Release struct {
 	Uploader string
 	// The human descriptions of changes applied since last release
 	Changes []string
+	// When the release has been made
+	Date time.Time `yaml:",omitempty"`
+}
+
+var (
+	upstreamVersionRegex = regexp.MustCompile(`^[0-9][A-Za-z0-9.+~-]*$`)
+	revisionRegex        = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// ValidateVersion make sure the given release version is valid
+// i.e <upstream-version>-<revision> with upstream version starting by a digit and a numeric revision
+func ValidateVersion(version string) error {
+	i := strings.LastIndex(version, "-")
+	if i == -1 {
+		return fmt.Errorf("invalid version %q: missing revision (expected <upstream-version>-<revision>)", version)
+	}
+
+	if upstream := version[:i]; !upstreamVersionRegex.MatchString(upstream) {
+		return fmt.Errorf("invalid version %q: invalid upstream version %q", version, upstream)
+	}
+	if revision := version[i+1:]; !revisionRegex.MatchString(revision) {
+		return fmt.Errorf("invalid version %q: invalid revision %q", version, revision)
+	}
+
+	return nil
+}
+
+// CompareVersions compares two valid release versions
+// it returns -1 if a < b, 0 if a == b and +1 if a > b
+// upstream versions are compared like Debian does (a ~ sorts before anything, even the end)
+// then the revisions are compared numerically
+func CompareVersions(a, b string) int {
+	aUpstream, aRevision := splitVersion(a)
+	bUpstream, bRevision := splitVersion(b)
+
+	if res := compareUpstream(aUpstream, bUpstream); res != 0 {
+		return res
+	}
+
+	return compareNumbers(aRevision, bRevision)
+}
+
+// NextRevision returns the next packaging revision of the given version (f.e 1.2.0-1 -> 1.2.0-2)
+func NextRevision(version string) (string, error) {
+	if err := ValidateVersion(version); err != nil {
+		return "", err
+	}
+
+	upstream, revision := splitVersion(version)
+	n, err := strconv.Atoi(revision)
+	if err != nil {
+		return "", fmt.Errorf("invalid version %q: %s", version, err)
+	}
+
+	return fmt.Sprintf("%s-%d", upstream, n+1), nil
+}
+
+// splitVersion returns the upstream version & the revision of version
+func splitVersion(version string) (string, string) {
+	i := strings.LastIndex(version, "-")
+	if i == -1 {
+		return version, "0"
+	}
+
+	return version[:i], version[i+1:]
+}
+
+// compareUpstream compares the upstream versions using the dpkg algorithm:
+// non digit parts are compared lexically (letters before non letters, ~ before everything)
+// & digit parts numerically
+func compareUpstream(a, b string) int {
+	for a != "" || b != "" {
+		var aPart, bPart string
+		aPart, a = splitPrefix(a, false)
+		bPart, b = splitPrefix(b, false)
+		if res := compareLexical(aPart, bPart); res != 0 {
+			return res
+		}
+
+		aPart, a = splitPrefix(a, true)
+		bPart, b = splitPrefix(b, true)
+		if res := compareNumbers(aPart, bPart); res != 0 {
+			return res
+		}
+	}
+
+	return 0
+}
+
+// splitPrefix split s after its longest prefix made of digits (or non digits)
+func splitPrefix(s string, digits bool) (string, string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) == digits {
+		i++
+	}
+
+	return s[:i], s[i:]
+}
+
+func compareLexical(a, b string) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var ac, bc int
+		if i < len(a) {
+			ac = lexicalOrder(a[i])
+		}
+		if i < len(b) {
+			bc = lexicalOrder(b[i])
+		}
+
+		if ac != bc {
+			if ac < bc {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// lexicalOrder returns the weight of c, the end of the string weighting 0
+func lexicalOrder(c byte) int {
+	switch {
+	case c == '~':
+		return -1
+	case isDigit(c):
+		return 0
+	case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		return int(c)
+	default:
+		return int(c) + 256
+	}
+}
+
+// compareNumbers compares two digit strings numerically (empty meaning 0)
+func compareNumbers(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+
+	switch {
+	case len(a) != len(b):
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
 }
 
 // LastRelease return the latest release from changelog
 func (c *Changelog) LastRelease() (Release, error) {
+	if len(c.Releases) == 0 {
+		return Release{}, errors.New("changelog has no release")
+	}
+
 	return c.Releases[len(c.Releases)-1], nil
 }
 
+// Validate make sure the releases versions are valid & strictly increasing
+func (c *Changelog) Validate() error {
+	for i, release := range c.Releases {
+		if err := ValidateVersion(release.Version); err != nil {
+			return err
+		}
+
+		if i > 0 && CompareVersions(c.Releases[i-1].Version, release.Version) >= 0 {
+			return fmt.Errorf("version %s is not greater than previous version %s",
+				release.Version, c.Releases[i-1].Version)
+		}
+	}
+
+	return nil
+}
+
+// AddChange adds the given change description to the latest release
+func (c *Changelog) AddChange(change string) error {
+	if len(c.Releases) == 0 {
+		return errors.New("changelog has no release")
+	}
+
+	last := &c.Releases[len(c.Releases)-1]
+	last.Changes = append(last.Changes, change)
+	return nil
+}
+
+// AddRelease appends a new release made now by uploader
+// the version must be greater than the latest release one
+func (c *Changelog) AddRelease(version, uploader string, changes []string) error {
+	if err := ValidateVersion(version); err != nil {
+		return err
+	}
+
+	if len(c.Releases) > 0 {
+		if last := c.Releases[len(c.Releases)-1].Version; CompareVersions(last, version) >= 0 {
+			return fmt.Errorf("version %s is not greater than latest version %s", version, last)
+		}
+	}
+
+	c.Releases = append(c.Releases, Release{
+		Version:  version,
+		Uploader: uploader,
+		Changes:  changes,
+		Date:     time.Now().UTC().Truncate(time.Second),
+	})
+
+	return nil
+}
+
+// SourceDate returns the timestamp to use for files produced from the release
+// SOURCE_DATE_EPOCH takes precedence over the release date, the unix epoch is used if none are set
+func (r Release) SourceDate() (time.Time, error) {
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		sec, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH: %s", err)
+		}
+		return time.Unix(sec, 0).UTC(), nil
+	}
+
+	if !r.Date.IsZero() {
+		return r.Date.UTC(), nil
+	}
+
+	return time.Unix(0, 0).UTC(), nil
+}
+
 // newChangelog create a brand new changelog
 func newChangelog(initialVersion, uploader string) Changelog {
 	return Changelog{
@@ -38,13 +272,14 @@ func newChangelog(initialVersion, uploader string) Changelog {
 			Version:  fmt.Sprintf("%s-1", initialVersion),
 			Uploader: uploader,
 			Changes:  []string{"Initial packaging"},
+			Date:     time.Now().UTC().Truncate(time.Second),
 		}},
 	}
 }
 
 // writeChangelog write the given changelog
 func writeChangelog(c Changelog, path string) error {
-	b, err := yaml.Marshal(c)
+	b, err := util.EncodeYAML(c)
 	if err != nil {
 		return err
 	}
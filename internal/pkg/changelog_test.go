@@ -1,6 +1,10 @@
 package pkg
 
-import "testing"
+import (
+	"os"
+	"testing"
+	"time"
+)
 
 func TestChangelog_LastRelease(t *testing.T) {
 	c := Changelog{Releases: []Release{}}
@@ -35,3 +39,147 @@ func TestNewChangelog(t *testing.T) {
 		t.Errorf("wrong changes (got %s)", got)
 	}
 }
+
+func TestRelease_SourceDate(t *testing.T) {
+	date := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	r := Release{Version: "1.0.0-1", Date: date}
+
+	os.Unsetenv("SOURCE_DATE_EPOCH")
+	if got, err := r.SourceDate(); err != nil || !got.Equal(date) {
+		t.Errorf("wrong source date (got %s, %v)", got, err)
+	}
+	if got, err := (Release{}).SourceDate(); err != nil || got.Unix() != 0 {
+		t.Errorf("wrong source date (got %s, %v)", got, err)
+	}
+
+	os.Setenv("SOURCE_DATE_EPOCH", "1000")
+	t.Cleanup(func() {
+		os.Unsetenv("SOURCE_DATE_EPOCH")
+	})
+	if got, err := r.SourceDate(); err != nil || got.Unix() != 1000 {
+		t.Errorf("SOURCE_DATE_EPOCH has not been used (got %s, %v)", got, err)
+	}
+
+	os.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+	if _, err := r.SourceDate(); err == nil {
+		t.Error("invalid SOURCE_DATE_EPOCH should be rejected")
+	}
+}
+
+func TestValidateVersion(t *testing.T) {
+	valid := []string{"1.0.0-1", "0.0~git202010011200-1", "1.0.0-rc1-12", "2.1+dfsg-3"}
+	for _, version := range valid {
+		if err := ValidateVersion(version); err != nil {
+			t.Errorf("%s should be valid (got %s)", version, err)
+		}
+	}
+
+	invalid := []string{"", "1.0.0", "v1.0.0-1", "1.0.0-", "1.0.0-a", "-1"}
+	for _, version := range invalid {
+		if err := ValidateVersion(version); err == nil {
+			t.Errorf("%s should be invalid", version)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.0.0-1", "1.0.0-1", 0},
+		{"1.0.0-1", "1.0.0-2", -1},
+		{"1.0.0-10", "1.0.0-9", 1},
+		{"1.0.0-5", "1.0.1-1", -1},
+		{"1.10.0-1", "1.9.0-1", 1},
+		{"1.0.0~rc1-1", "1.0.0-1", -1},
+		{"1.0.0~rc1-1", "1.0.0~rc2-1", -1},
+		{"1.0.0+git1-1", "1.0.0-1", 1},
+		{"1.0.0a-1", "1.0.0-1", 1},
+		{"1.0.0a-1", "1.0.0+-1", -1},
+		{"1.0-1", "1.0.0-1", -1},
+		{"01.0-1", "1.0-1", 0},
+	}
+
+	for _, test := range tests {
+		if got := CompareVersions(test.a, test.b); got != test.expected {
+			t.Errorf("CompareVersions(%s, %s): got %d, expected %d", test.a, test.b, got, test.expected)
+		}
+		if got := CompareVersions(test.b, test.a); got != -test.expected {
+			t.Errorf("CompareVersions(%s, %s): got %d, expected %d", test.b, test.a, got, -test.expected)
+		}
+	}
+}
+
+func TestNextRevision(t *testing.T) {
+	version, err := NextRevision("1.2.0-rc1-9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "1.2.0-rc1-10" {
+		t.Errorf("wrong version: %s", version)
+	}
+
+	if _, err := NextRevision("1.2.0"); err == nil {
+		t.Error("NextRevision should have failed")
+	}
+}
+
+func TestChangelog_Validate(t *testing.T) {
+	c := Changelog{Releases: []Release{{Version: "1.0.0-1"}, {Version: "1.0.0-2"}, {Version: "1.1.0-1"}}}
+	if err := c.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	c.Releases = append(c.Releases, Release{Version: "1.0.9-1"})
+	if err := c.Validate(); err == nil {
+		t.Error("Validate should have failed")
+	}
+
+	c = Changelog{Releases: []Release{{Version: "1.0.0-1"}, {Version: "1.0.0-1"}}}
+	if err := c.Validate(); err == nil {
+		t.Error("Validate should have failed")
+	}
+
+	c = Changelog{Releases: []Release{{Version: "1.0.0"}}}
+	if err := c.Validate(); err == nil {
+		t.Error("Validate should have failed")
+	}
+}
+
+func TestChangelog_AddRelease(t *testing.T) {
+	var c Changelog
+	if _, err := c.LastRelease(); err == nil {
+		t.Error("LastRelease should have failed")
+	}
+	if err := c.AddChange("Fix build"); err == nil {
+		t.Error("AddChange should have failed")
+	}
+
+	if err := c.AddRelease("1.0.0-1", "John Doe <john@doe.com>", []string{"Initial packaging"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddRelease("1.0.0-1", "John Doe <john@doe.com>", nil); err == nil {
+		t.Error("AddRelease should have failed")
+	}
+	if err := c.AddRelease("0.9.0-1", "John Doe <john@doe.com>", nil); err == nil {
+		t.Error("AddRelease should have failed")
+	}
+	if err := c.AddRelease("1.0.1", "John Doe <john@doe.com>", nil); err == nil {
+		t.Error("AddRelease should have failed")
+	}
+	if err := c.AddChange("Fix build"); err != nil {
+		t.Fatal(err)
+	}
+
+	last, err := c.LastRelease()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.Version != "1.0.0-1" || last.Uploader != "John Doe <john@doe.com>" || last.Date.IsZero() {
+		t.Errorf("wrong release: %+v", last)
+	}
+	if len(last.Changes) != 2 || last.Changes[1] != "Fix build" {
+		t.Errorf("wrong changes: %v", last.Changes)
+	}
+}
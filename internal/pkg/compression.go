@@ -0,0 +1,86 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression represent a package compression algorithm
+type Compression string
+
+const (
+	// NoCompression produce plain tar packages
+	NoCompression Compression = "none"
+	// Gzip produce gzip compressed packages
+	Gzip Compression = "gzip"
+	// Zstd produce zstandard compressed packages
+	Zstd Compression = "zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// ParseCompression returns the compression matching given name
+// an empty name means no compression
+func ParseCompression(name string) (Compression, error) {
+	switch c := Compression(name); c {
+	case "":
+		return NoCompression, nil
+	case NoCompression, Gzip, Zstd:
+		return c, nil
+	default:
+		return "", fmt.Errorf("non managed compression: %s", name)
+	}
+}
+
+// compress returns a writer compressing data written to w using given compression
+func compress(w io.Writer, c Compression) (io.WriteCloser, error) {
+	switch c {
+	case "", NoCompression:
+		return nopWriteCloser{w}, nil
+	case Gzip:
+		return gzip.NewWriter(w), nil
+	case Zstd:
+		return zstd.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("non managed compression: %s", c)
+	}
+}
+
+// decompress returns a reader decompressing r
+// the compression is detected using the magic bytes, uncompressed data is returned as-is
+func decompress(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+
+	// Peek may return less bytes than asked for small inputs
+	magic, _ := br.Peek(len(zstdMagic))
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(magic, zstdMagic):
+		d, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	default:
+		return ioutil.NopCloser(br), nil
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,59 @@
+package pkg
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseCompression(t *testing.T) {
+	tests := map[string]Compression{"": NoCompression, "none": NoCompression, "gzip": Gzip, "zstd": Zstd}
+	for name, expected := range tests {
+		c, err := ParseCompression(name)
+		if err != nil {
+			t.Error(err)
+		}
+		if c != expected {
+			t.Errorf("wrong compression (got %s want %s)", c, expected)
+		}
+	}
+
+	if _, err := ParseCompression("bzip2"); err == nil {
+		t.Error("ParseCompression should have failed")
+	}
+}
+
+func TestReadWrite_Compressed(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gopkg_*")
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	txtFile, _ := ioutil.TempFile(dir, "*.txt")
+	txtFile.WriteString("This is a txt file")
+	txtFile.Close()
+
+	for _, c := range []Compression{NoCompression, Gzip, Zstd} {
+		path := filepath.Join(dir, string(c)+".pkg")
+		if err := Write(path, []Entry{{txtFile.Name(), "txtfile.txt"}}, true, c, time.Time{}); err != nil {
+			t.Errorf("failed to create %s archive: %s", c, err)
+		}
+
+		p, err := ReadFile(path)
+		if err != nil {
+			t.Errorf("failed to read %s archive: %s", c, err)
+			continue
+		}
+
+		files, err := p.Files()
+		if err != nil {
+			t.Errorf("failed to read %s archive files: %s", c, err)
+			continue
+		}
+		if string(files["txtfile.txt"]) != "This is a txt file" {
+			t.Errorf("Txt file could not be read from %s archive.", c)
+		}
+	}
+}
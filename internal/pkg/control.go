@@ -55,3 +55,22 @@ func ReadCtrlDirectory(path string) (ControlMeta, Changelog, error) {
 
 	return m, c, nil
 }
+
+// ReadCtrlChangelog reads the changelog of the control directory at given path
+func ReadCtrlChangelog(path string) (Changelog, error) {
+	return readChangelog(filepath.Join(path, GoPkgDir))
+}
+
+// WriteCtrlChangelog validates & writes the changelog of the control directory at given path
+func WriteCtrlChangelog(path string, c Changelog) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	return writeChangelog(c, filepath.Join(path, GoPkgDir))
+}
+
+// WriteCtrlMetadata writes the metadata of the control directory at given path
+func WriteCtrlMetadata(path string, m ControlMeta) error {
+	return writeControlMeta(m, filepath.Join(path, GoPkgDir))
+}
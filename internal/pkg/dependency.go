@@ -0,0 +1,36 @@
+package pkg
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// dependencyRegex matches the build dependencies: name or name (>= version)
+var dependencyRegex = regexp.MustCompile(`^([^\s()]+)(?:\s+\(>=\s*([^\s()]+)\))?$`)
+
+// Dependency is a build dependency, optionally requiring a minimum upstream version
+type Dependency struct {
+	// Name is the source package name (f.e github.com-foo-bar-src)
+	Name string
+	// Version is the minimum upstream version required (may be empty)
+	Version string
+}
+
+// ParseDependency parse a build dependency formatted as name or name (>= version)
+func ParseDependency(s string) (Dependency, error) {
+	match := dependencyRegex.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return Dependency{}, fmt.Errorf("invalid dependency %q (expected name or name (>= version))", s)
+	}
+
+	return Dependency{Name: match[1], Version: match[2]}, nil
+}
+
+func (d Dependency) String() string {
+	if d.Version == "" {
+		return d.Name
+	}
+
+	return fmt.Sprintf("%s (>= %s)", d.Name, d.Version)
+}
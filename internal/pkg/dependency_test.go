@@ -0,0 +1,39 @@
+package pkg
+
+import "testing"
+
+func TestParseDependency(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected Dependency
+	}{
+		{"github.com-foo-bar-src", Dependency{Name: "github.com-foo-bar-src"}},
+		{"github.com-foo-bar-src (>= 1.2.0)", Dependency{Name: "github.com-foo-bar-src", Version: "1.2.0"}},
+		{" gopkg.in-yaml.v2-src (>=2.3.0) ", Dependency{Name: "gopkg.in-yaml.v2-src", Version: "2.3.0"}},
+	}
+
+	for _, test := range tests {
+		dep, err := ParseDependency(test.value)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.value, err)
+		}
+		if dep != test.expected {
+			t.Errorf("%s: got %+v, expected %+v", test.value, dep, test.expected)
+		}
+	}
+
+	for _, value := range []string{"", "foo bar", "foo (1.2.0)", "foo (>= 1.2.0", "foo (>= )"} {
+		if _, err := ParseDependency(value); err == nil {
+			t.Errorf("%s: ParseDependency should have failed", value)
+		}
+	}
+}
+
+func TestDependency_String(t *testing.T) {
+	if s := (Dependency{Name: "foo-src"}).String(); s != "foo-src" {
+		t.Errorf("wrong dependency: %s", s)
+	}
+	if s := (Dependency{Name: "foo-src", Version: "1.0.0"}).String(); s != "foo-src (>= 1.0.0)" {
+		t.Errorf("wrong dependency: %s", s)
+	}
+}
@@ -0,0 +1,24 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The errors below are shared by the packages dealing with .pkg files
+// callers should test them using errors.Is since they are usually wrapped
+var (
+	// ErrNotFound is returned when a package cannot be found
+	ErrNotFound = errors.New("package not found")
+	// ErrWrongTarget is returned when a package is not compatible with the current os/arch
+	ErrWrongTarget = errors.New("package is not compatible")
+	// ErrAlreadyInstalled is returned when a package is already installed
+	ErrAlreadyInstalled = errors.New("package is already installed")
+	// ErrCorruptPackage is returned when a package cannot be read or doesn't match its checksum
+	ErrCorruptPackage = errors.New("corrupt package")
+	// ErrInvalidSignature is returned when a package signature is not valid
+	ErrInvalidSignature = errors.New("invalid package signature")
+)
+
+// ErrUnsafePath is returned when a package entry would be extracted outside of its root
+var ErrUnsafePath = fmt.Errorf("%w: unsafe path", ErrCorruptPackage)
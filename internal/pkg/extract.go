@@ -0,0 +1,179 @@
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	fileutil "github.com/go-pkg-org/gopkg/internal/util/file"
+	"github.com/rs/zerolog/log"
+)
+
+// EntryFilter is called for each entry before extraction
+// it may rename the entry (h.Name, relative to the extraction root) or adjust its mode.
+// Entries for which it returns false are skipped.
+type EntryFilter func(h *Header) bool
+
+// Extract safely extracts the package entries into root and returns the extracted paths
+// (directories excepted). Absolute paths, `..` components, duplicate entries,
+// symlinks pointing outside of root and entries written through a symlink
+// are rejected with ErrUnsafePath.
+func Extract(f File, root string, filter EntryFilter) ([]string, error) {
+	var files []string
+	seen := map[string]bool{}
+
+	err := f.Walk(func(h Header, r io.Reader) error {
+		if err := ValidatePath(h.Name); err != nil {
+			return err
+		}
+		if seen[h.Name] {
+			return fmt.Errorf("%w: duplicate entry %s", ErrUnsafePath, h.Name)
+		}
+		seen[h.Name] = true
+
+		if filter != nil && !filter(&h) {
+			return nil
+		}
+
+		// the filter may have renamed the entry
+		if err := ValidatePath(h.Name); err != nil {
+			return err
+		}
+
+		target := filepath.Join(root, filepath.FromSlash(h.Name))
+		if !isInside(root, target) {
+			return fmt.Errorf("%w: %s", ErrUnsafePath, h.Name)
+		}
+		if err := checkParents(root, h); err != nil {
+			return err
+		}
+
+		log.Trace().Str("path", target).Msg("Writing file")
+		if err := extractEntry(root, target, h, r); err != nil {
+			return err
+		}
+
+		if h.Type != Directory {
+			files = append(files, target)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// ValidatePath make sure name is a clean relative path: absolute paths,
+// backslashes & `..` components are rejected with ErrUnsafePath
+func ValidatePath(name string) error {
+	if name == "" || strings.Contains(name, `\`) || path.IsAbs(name) ||
+		filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return fmt.Errorf("%w: %s", ErrUnsafePath, name)
+		}
+	}
+
+	return nil
+}
+
+// checkParents make sure no existing component of the entry path is a symlink
+// (a chain of in-root symlinks could otherwise be used to write outside of root)
+func checkParents(root string, h Header) error {
+	parts := strings.Split(h.Name, "/")
+	current := root
+
+	for i, part := range parts {
+		current = filepath.Join(current, part)
+
+		fi, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if fi.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+
+		// the entry itself may only replace an existing symlink by another one
+		if i == len(parts)-1 && h.Type == Symlink {
+			return nil
+		}
+
+		return fmt.Errorf("%w: %s is written through symlink %s", ErrUnsafePath, h.Name, current)
+	}
+
+	return nil
+}
+
+// extractEntry creates the entry described by h at target, reading its content from r
+// root is the extraction root: symlinks pointing outside of it are rejected
+func extractEntry(root, target string, h Header, r io.Reader) error {
+	switch h.Type {
+	case Directory:
+		// make sure we can write into the directory
+		return os.MkdirAll(target, h.Mode|0700)
+	case Symlink:
+		if !isSafeLink(root, target, h.Linkname) {
+			return fmt.Errorf("%w: %s links to %s", ErrUnsafePath, h.Name, h.Linkname)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0750); err != nil {
+			return err
+		}
+		if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		return os.Symlink(h.Linkname, target)
+	case RegularFile, "":
+		if err := os.MkdirAll(filepath.Dir(target), 0750); err != nil {
+			return err
+		}
+
+		mode := h.Mode
+		if mode == 0 {
+			mode = 0644
+		}
+
+		if err := fileutil.WriteReader(target, r, mode); err != nil {
+			return err
+		}
+
+		// the file may have existed with other permissions
+		return os.Chmod(target, mode)
+	default:
+		return fmt.Errorf("%w: unsupported entry type %s for %s", ErrCorruptPackage, h.Type, h.Name)
+	}
+}
+
+// isSafeLink determinate if a symlink created at target and pointing to link stays inside root
+func isSafeLink(root, target, link string) bool {
+	if link == "" || filepath.IsAbs(link) || strings.HasPrefix(link, "/") {
+		return false
+	}
+
+	resolved := filepath.Join(filepath.Dir(target), filepath.FromSlash(link))
+	return isInside(root, resolved)
+}
+
+// isInside determinate if path is root or one of its descendants
+func isInside(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
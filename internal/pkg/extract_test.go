@@ -0,0 +1,261 @@
+package pkg
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestModesAndTypes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks & modes are not supported on windows")
+	}
+
+	dir, _ := ioutil.TempDir("", "gopkg_*")
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	srcDir := filepath.Join(dir, "src")
+	os.MkdirAll(filepath.Join(srcDir, "empty"), 0700)
+	ioutil.WriteFile(filepath.Join(srcDir, "script.sh"), []byte("#!/bin/sh"), 0700)
+	ioutil.WriteFile(filepath.Join(srcDir, "private.txt"), []byte("private"), 0600)
+	os.Symlink("script.sh", filepath.Join(srcDir, "link.sh"))
+
+	entries, err := CreateEntries(srcDir, "", []string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Write(filepath.Join(dir, "out.pkg"), entries, true, NoCompression, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := OpenFile(filepath.Join(dir, "out.pkg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	installDir := filepath.Join(dir, "install")
+	headers := map[string]Header{}
+	if _, err := Extract(p, installDir, func(h *Header) bool {
+		headers[h.Name] = *h
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the modes are normalized
+	if h := headers["empty"]; h.Type != Directory || h.Mode != 0755 {
+		t.Errorf("wrong directory header: %+v", h)
+	}
+	if h := headers["script.sh"]; h.Type != RegularFile || h.Mode != 0755 {
+		t.Errorf("wrong file header: %+v", h)
+	}
+	if h := headers["private.txt"]; h.Type != RegularFile || h.Mode != 0644 {
+		t.Errorf("wrong file header: %+v", h)
+	}
+	if h := headers["link.sh"]; h.Type != Symlink || h.Linkname != "script.sh" {
+		t.Errorf("wrong symlink header: %+v", h)
+	}
+
+	if fi, err := os.Stat(filepath.Join(installDir, "empty")); err != nil || !fi.IsDir() {
+		t.Error("empty directory has not been extracted")
+	}
+	if fi, err := os.Stat(filepath.Join(installDir, "script.sh")); err != nil || fi.Mode().Perm() != 0755 {
+		t.Error("script mode has not been preserved")
+	}
+	if target, err := os.Readlink(filepath.Join(installDir, "link.sh")); err != nil || target != "script.sh" {
+		t.Error("symlink has not been extracted")
+	}
+}
+
+func TestExtractEntry_UnsafeLink(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gopkg_*")
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	links := []string{"../../.bashrc", "/etc/passwd", "a/../../../b"}
+	for _, link := range links {
+		h := Header{Name: "sub/link", Type: Symlink, Linkname: link}
+		err := extractEntry(dir, filepath.Join(dir, "sub", "link"), h, strings.NewReader(""))
+		if !errors.Is(err, ErrUnsafePath) {
+			t.Errorf("extractEntry should have rejected link to %s (got %v)", link, err)
+		}
+	}
+
+	h := Header{Name: "sub/link", Type: Symlink, Linkname: "../file"}
+	if err := extractEntry(dir, filepath.Join(dir, "sub", "link"), h, strings.NewReader("")); err != nil {
+		t.Errorf("extractEntry should have accepted link to ../file (got %v)", err)
+	}
+}
+
+func TestExtract_Malicious(t *testing.T) {
+	tests := map[string][]*tar.Header{
+		"absolute path": {
+			{Name: "/tmp/evil", Typeflag: tar.TypeReg, Mode: 0644},
+		},
+		"parent directory": {
+			{Name: "../evil", Typeflag: tar.TypeReg, Mode: 0644},
+		},
+		"nested parent directory": {
+			{Name: "a/b/../../../evil", Typeflag: tar.TypeReg, Mode: 0644},
+		},
+		"backslash": {
+			{Name: `..\evil`, Typeflag: tar.TypeReg, Mode: 0644},
+		},
+		"symlink outside root": {
+			{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "../../etc"},
+		},
+		"absolute symlink": {
+			{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc"},
+		},
+		"chained symlinks": {
+			{Name: "x", Typeflag: tar.TypeSymlink, Linkname: "."},
+			{Name: "x/y", Typeflag: tar.TypeSymlink, Linkname: ".."},
+			{Name: "y/evil", Typeflag: tar.TypeReg, Mode: 0644},
+		},
+		"file through symlink": {
+			{Name: "x", Typeflag: tar.TypeSymlink, Linkname: "."},
+			{Name: "x/evil", Typeflag: tar.TypeReg, Mode: 0644},
+		},
+	}
+
+	for name, headers := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir, _ := ioutil.TempDir("", "gopkg_*")
+			t.Cleanup(func() {
+				os.RemoveAll(dir)
+			})
+
+			f, err := Read(bytes.NewReader(maliciousArchive(t, headers)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			root := filepath.Join(dir, "root")
+			if _, err := Extract(f, root, nil); !errors.Is(err, ErrUnsafePath) {
+				t.Errorf("Extract should have returned ErrUnsafePath (got %v)", err)
+			}
+
+			if _, err := os.Stat(filepath.Join(dir, "evil")); err == nil {
+				t.Error("file has been written outside of the extraction root")
+			}
+		})
+	}
+}
+
+func TestExtract_Duplicate(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gopkg_*")
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	b := maliciousArchive(t, []*tar.Header{
+		{Name: "file", Typeflag: tar.TypeReg, Mode: 0644},
+		{Name: "file", Typeflag: tar.TypeReg, Mode: 0755},
+	})
+
+	if _, err := Read(bytes.NewReader(b)); !errors.Is(err, ErrUnsafePath) {
+		t.Errorf("Read should have returned ErrUnsafePath (got %v)", err)
+	}
+
+	pkgPath := filepath.Join(dir, "evil.pkg")
+	if err := ioutil.WriteFile(pkgPath, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenFile(pkgPath); !errors.Is(err, ErrUnsafePath) {
+		t.Errorf("OpenFile should have returned ErrUnsafePath (got %v)", err)
+	}
+
+	// the package may have been modified since it has been opened
+	f := duplicateFile{name: "file"}
+	if _, err := Extract(f, dir, nil); !errors.Is(err, ErrUnsafePath) {
+		t.Errorf("Extract should have returned ErrUnsafePath (got %v)", err)
+	}
+}
+
+// duplicateFile is a package containing the same entry twice
+type duplicateFile struct {
+	File
+	name string
+}
+
+func (f duplicateFile) Walk(fn WalkFunc) error {
+	for i := 0; i < 2; i++ {
+		if err := fn(Header{Name: f.name, Type: RegularFile, Mode: 0644}, strings.NewReader("")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func TestExtract_Filter(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gopkg_*")
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	f, err := Read(bytes.NewReader(maliciousArchive(t, []*tar.Header{
+		{Name: "bin/tool", Typeflag: tar.TypeReg, Mode: 0755},
+		{Name: "README", Typeflag: tar.TypeReg, Mode: 0644},
+	})))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a filter cannot be used to escape the extraction root
+	if _, err := Extract(f, dir, func(h *Header) bool {
+		h.Name = "../" + h.Name
+		return true
+	}); !errors.Is(err, ErrUnsafePath) {
+		t.Errorf("Extract should have returned ErrUnsafePath (got %v)", err)
+	}
+
+	files, err := Extract(f, dir, func(h *Header) bool {
+		if !strings.HasPrefix(h.Name, "bin/") {
+			return false
+		}
+		h.Name = strings.TrimPrefix(h.Name, "bin/")
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0] != filepath.Join(dir, "tool") {
+		t.Errorf("wrong extracted files: %v", files)
+	}
+}
+
+// maliciousArchive build a package archive containing the given raw tar headers
+func maliciousArchive(t *testing.T, headers []*tar.Header) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+
+	content := []byte("alias: evil\n")
+	if err := tw.WriteHeader(&tar.Header{Name: "package.yaml", Typeflag: tar.TypeReg, Mode: 0644,
+		Size: int64(len(content))}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Write(content)
+
+	for _, h := range headers {
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
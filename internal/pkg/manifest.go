@@ -0,0 +1,87 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestFile is the package entry describing every other entries
+const ManifestFile = "manifest.yaml"
+
+// ErrManifestMismatch is returned when the package entries does not match its manifest
+var ErrManifestMismatch = fmt.Errorf("%w: manifest mismatch", ErrCorruptPackage)
+
+// ManifestEntry describe a package entry
+type ManifestEntry struct {
+	Type     EntryType
+	Size     int64       `yaml:",omitempty"`
+	Mode     os.FileMode `yaml:",omitempty"`
+	Linkname string      `yaml:",omitempty"`
+	// SHA256 is the hex encoded checksum of the entry content (regular files only)
+	SHA256 string `yaml:"sha256,omitempty"`
+}
+
+// Manifest list the package entries, indexed by name
+type Manifest map[string]ManifestEntry
+
+// IsMetaFile returns true if the named entry describe the package instead of providing content
+func IsMetaFile(name string) bool {
+	return name == "package.yaml" || name == "package.yml" || name == ManifestFile || name == FormatFile
+}
+
+// hashEntry returns the manifest entry describing h, the content is read from r
+func hashEntry(h Header, r io.Reader) (ManifestEntry, error) {
+	entry := ManifestEntry{Type: h.Type, Mode: h.Mode, Linkname: h.Linkname}
+	if h.Type != RegularFile {
+		return entry, nil
+	}
+
+	hash := sha256.New()
+	n, err := io.Copy(hash, r)
+	if err != nil {
+		return ManifestEntry{}, err
+	}
+
+	entry.Size = n
+	entry.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return entry, nil
+}
+
+// verify make sure the entries found in the package match the manifest
+func (m Manifest) verify(found Manifest) error {
+	for name, entry := range found {
+		if name == ManifestFile {
+			continue
+		}
+
+		expected, exist := m[name]
+		if !exist {
+			return fmt.Errorf("%w: unexpected entry %s", ErrManifestMismatch, name)
+		}
+		if entry != expected {
+			return fmt.Errorf("%w: entry %s has been modified", ErrManifestMismatch, name)
+		}
+	}
+
+	for name := range m {
+		if _, exist := found[name]; !exist {
+			return fmt.Errorf("%w: missing entry %s", ErrManifestMismatch, name)
+		}
+	}
+
+	return nil
+}
+
+func parseManifest(b []byte) (Manifest, error) {
+	var m Manifest
+	if err := yaml.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("%w: invalid manifest: %s", ErrCorruptPackage, err)
+	}
+
+	return m, nil
+}
@@ -0,0 +1,127 @@
+package pkg
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestWrite_Manifest(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gopkg_*")
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	ioutil.WriteFile(filepath.Join(dir, "package.yaml"), []byte("alias: foo/bar\n"), 0640)
+	ioutil.WriteFile(filepath.Join(dir, "tool"), []byte("binary"), 0755)
+
+	path := filepath.Join(dir, "out.pkg")
+	if err := Write(path, []Entry{
+		{filepath.Join(dir, "package.yaml"), "package.yaml"},
+		{filepath.Join(dir, "tool"), "bin/tool"},
+	}, true, Gzip, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := p.Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 3 || m[FormatFile].Type != RegularFile {
+		t.Errorf("wrong manifest entries: %v", m)
+	}
+
+	entry := m["bin/tool"]
+	if entry.Type != RegularFile || entry.Size != 6 || entry.Mode != 0755 || entry.SHA256 != sha256Hex("binary") {
+		t.Errorf("wrong manifest entry: %+v", entry)
+	}
+}
+
+func TestRead_Manifest(t *testing.T) {
+	valid := Manifest{
+		"package.yaml": {Type: RegularFile, Size: 14, Mode: 0644, SHA256: sha256Hex("alias: foo/bar")},
+		"file":         {Type: RegularFile, Size: 5, Mode: 0644, SHA256: sha256Hex("hello")},
+	}
+
+	tests := map[string]struct {
+		files map[string]string
+		err   error
+	}{
+		"valid": {
+			files: map[string]string{"package.yaml": "alias: foo/bar", "file": "hello"},
+		},
+		"modified entry": {
+			files: map[string]string{"package.yaml": "alias: foo/bar", "file": "world"},
+			err:   ErrManifestMismatch,
+		},
+		"missing entry": {
+			files: map[string]string{"package.yaml": "alias: foo/bar"},
+			err:   ErrManifestMismatch,
+		},
+		"unexpected entry": {
+			files: map[string]string{"package.yaml": "alias: foo/bar", "file": "hello", "evil": "evil"},
+			err:   ErrManifestMismatch,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			b := manifestArchive(t, valid, test.files)
+
+			if _, err := Read(bytes.NewReader(b)); !errors.Is(err, test.err) {
+				t.Errorf("Read: wrong error (got %v want %v)", err, test.err)
+			}
+
+			dir, _ := ioutil.TempDir("", "gopkg_*")
+			t.Cleanup(func() {
+				os.RemoveAll(dir)
+			})
+			ioutil.WriteFile(filepath.Join(dir, "test.pkg"), b, 0644)
+			if _, err := OpenFile(filepath.Join(dir, "test.pkg")); !errors.Is(err, test.err) {
+				t.Errorf("OpenFile: wrong error (got %v want %v)", err, test.err)
+			}
+		})
+	}
+}
+
+// manifestArchive build a package archive made of given files & manifest
+func manifestArchive(t *testing.T, m Manifest, files map[string]string) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+
+	b, _ := yaml.Marshal(m)
+	files[ManifestFile] = string(b)
+
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644,
+			Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(content))
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,17 +1,26 @@
 package pkg
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
 
+	"github.com/go-pkg-org/gopkg/internal/util"
 	fileutil "github.com/go-pkg-org/gopkg/internal/util/file"
-	"gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
 )
 
 const metadataFile = "metadata.yaml"
 
+// ErrInvalidMetadata is returned when the control metadata file is not valid
+var ErrInvalidMetadata = errors.New("invalid metadata")
+
 // ControlMeta represent the control package metadata
 type ControlMeta struct {
 	// The Go import path
@@ -20,16 +29,19 @@ type ControlMeta struct {
 	// i.e who take the responsibility for uploading & managing it
 	Maintainers []string
 	// The package build dependencies (i.e what we need to pull before building the package)
+	// formatted as name or name (>= version)
 	BuildDependencies []string `yaml:"build_dependencies"`
 	// List of the packages built by this control package
 	Packages []Meta
+	// Watch describe how to detect new upstream releases
+	Watch *Watch `yaml:"watch,omitempty"`
 }
 
 // Meta represent a package information
 type Meta struct {
 	// The package alias (i.e what the user will use to identify the package)
 	Alias string
-	// Main is the full path to the file containing the `func main()` sequence
+	// Main is the main package directory (f.e ./cmd/foo), relative to the sources root
 	Main string `yaml:"main,omitempty"`
 	// BinName is the name of the binary that will be installed
 	BinName string
@@ -37,10 +49,61 @@ type Meta struct {
 	Description string
 	// Targets describe the build target (os,arches)
 	Targets map[string][]string `yaml:"targets,omitempty"`
+	// The build settings below may use the BuildVars template variables (f.e {{.Version}})
+	// Tags are the build tags passed to go build
+	Tags []string `yaml:"tags,omitempty"`
+	// LdFlags are passed to go build -ldflags (f.e -X main.version={{.Version}})
+	LdFlags string `yaml:"ldflags,omitempty"`
+	// TrimPath removes the file system paths from the binary (default to true)
+	TrimPath *bool `yaml:"trimpath,omitempty"`
+	// CGOEnabled set CGO_ENABLED when building (default to the go tool behavior)
+	CGOEnabled *bool `yaml:"cgo_enabled,omitempty"`
+	// Env are extra environment variables (KEY=VALUE) set when building
+	Env []string `yaml:"env,omitempty"`
 	// These fields below are copied into the package.yaml definition
 	TargetOS       string `yaml:"target_os,omitempty"`
 	TargetArch     string `yaml:"target_arch,omitempty"`
 	ReleaseVersion string `yaml:"release_version,omitempty"`
+	// Patches are the downstream patches applied on the sources (source packages only)
+	Patches []string `yaml:"patches,omitempty"`
+}
+
+// BuildVars are the variables available in the build settings templates
+type BuildVars struct {
+	// Version is the release version (f.e 1.2.0-1)
+	Version string
+	// UpstreamVersion is the release version without the revision (f.e 1.2.0)
+	UpstreamVersion string
+	// Commit is the hash of the source commit if the sources are a git repository
+	Commit string
+	// OS & Arch are the current build target
+	OS   string
+	Arch string
+}
+
+// NewBuildVars returns the build variables for the given release version & commit
+func NewBuildVars(version, commit string) BuildVars {
+	upstreamVersion := version
+	if i := strings.LastIndex(version, "-"); i != -1 {
+		upstreamVersion = version[:i]
+	}
+
+	return BuildVars{Version: version, UpstreamVersion: upstreamVersion, Commit: commit}
+}
+
+// Expand executes s as a template using the build variables
+func (v BuildVars) Expand(s string) (string, error) {
+	tmpl, err := template.New("").Parse(s)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, v); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
 }
 
 // IsSource determinate if package is a source one
@@ -49,7 +112,7 @@ func (m *Meta) IsSource() bool {
 }
 
 func writeControlMeta(m ControlMeta, path string) error {
-	b, err := yaml.Marshal(m)
+	b, err := util.EncodeYAML(m)
 	if err != nil {
 		return err
 	}
@@ -57,6 +120,8 @@ func writeControlMeta(m ControlMeta, path string) error {
 	return ioutil.WriteFile(fmt.Sprintf("%s/%s", path, metadataFile), b, 0640)
 }
 
+// readControlMetadata reads the metadata file of the control directory located at path
+// the file is decoded strictly (unknown fields are errors) & validated
 func readControlMetadata(path string) (ControlMeta, error) {
 	var m ControlMeta
 
@@ -65,15 +130,149 @@ func readControlMetadata(path string) (ControlMeta, error) {
 		return ControlMeta{}, err
 	}
 
-	f, err := os.Open(path)
+	b, err := ioutil.ReadFile(path)
 	if err != nil {
 		return ControlMeta{}, err
 	}
-	defer f.Close()
 
-	if err := yaml.NewDecoder(f).Decode(&m); err != nil {
-		return ControlMeta{}, err
+	// unknown fields are reported by the decoder, the nodes line numbers are used for the semantic problems
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&m); err != nil {
+		return ControlMeta{}, fmt.Errorf("%w: %s: %s", ErrInvalidMetadata, path, err)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(b, &root); err != nil {
+		return ControlMeta{}, fmt.Errorf("%w: %s: %s", ErrInvalidMetadata, path, err)
+	}
+
+	// Main files are relative to the source directory
+	if problems := m.validate(filepath.Dir(filepath.Dir(path)), &root); len(problems) > 0 {
+		return ControlMeta{}, fmt.Errorf("%w: %s:\n  %s", ErrInvalidMetadata, path, strings.Join(problems, "\n  "))
 	}
 
 	return m, nil
 }
+
+// validate returns the semantic problems of the metadata, prefixed by their line number
+// root is the parsed metadata file & sourceDir the directory the Main files are relative to
+func (m *ControlMeta) validate(sourceDir string, root *yaml.Node) []string {
+	var problems []string
+	report := func(line int, format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf("line %d: %s", line, fmt.Sprintf(format, args...)))
+	}
+
+	if strings.TrimSpace(m.ImportPath) == "" {
+		report(lookupNode(root, "importpath").Line, "importpath is empty")
+	}
+
+	for i, dep := range m.BuildDependencies {
+		if _, err := ParseDependency(dep); err != nil {
+			report(lookupNode(root, "build_dependencies", i).Line, "build_dependencies[%d]: %s", i, err)
+		}
+	}
+
+	if m.Watch != nil {
+		if _, err := m.Watch.tagPattern(); err != nil {
+			report(lookupNode(root, "watch", "tag_pattern").Line, "watch: %s", err)
+		}
+		for i, rule := range m.Watch.VersionMangle {
+			if _, err := parseMangleRule(rule); err != nil {
+				report(lookupNode(root, "watch", "version_mangle", i).Line, "watch: %s", err)
+			}
+		}
+	}
+
+	for i, p := range m.Packages {
+		pkgNode := lookupNode(root, "packages", i)
+
+		if strings.TrimSpace(p.Alias) == "" {
+			report(lookupNode(pkgNode, "alias").Line, "packages[%d]: alias is empty", i)
+		}
+
+		if p.Main != "" {
+			if _, err := os.Stat(filepath.Join(sourceDir, p.Main)); err != nil {
+				report(lookupNode(pkgNode, "main").Line, "packages[%d]: main %s does not exist", i, p.Main)
+			}
+		}
+
+		if _, err := (BuildVars{}).Expand(p.LdFlags); err != nil {
+			report(lookupNode(pkgNode, "ldflags").Line, "packages[%d]: invalid ldflags: %s", i, err)
+		}
+		for j, env := range p.Env {
+			line := lookupNode(pkgNode, "env", j).Line
+			if !strings.Contains(env, "=") || strings.HasPrefix(env, "=") {
+				report(line, "packages[%d]: invalid env %q (expected KEY=VALUE)", i, env)
+			} else if _, err := (BuildVars{}).Expand(env); err != nil {
+				report(line, "packages[%d]: invalid env %q: %s", i, env, err)
+			}
+		}
+
+		// binary packages are built for their targets only
+		if !p.IsSource() && len(p.Targets) == 0 {
+			report(lookupNode(pkgNode, "targets").Line, "packages[%d]: no targets", i)
+		}
+
+		var oses []string
+		for goos := range p.Targets {
+			oses = append(oses, goos)
+		}
+		sort.Strings(oses)
+
+		for _, goos := range oses {
+			osNode := lookupNode(pkgNode, "targets", goos)
+			if !IsSupportedOS(goos) {
+				report(osNode.Line, "packages[%d]: unsupported os %s", i, goos)
+				continue
+			}
+			if len(p.Targets[goos]) == 0 {
+				report(osNode.Line, "packages[%d]: no arch for os %s", i, goos)
+			}
+
+			for j, arch := range p.Targets[goos] {
+				if !IsSupportedTarget(goos, arch) {
+					report(lookupNode(osNode, j).Line, "packages[%d]: unsupported arch %s for os %s", i, arch, goos)
+				}
+			}
+		}
+	}
+
+	return problems
+}
+
+// lookupNode returns the node located at given path, made of mapping keys (string)
+// & sequence indexes (int). The deepest node found is returned if the path does not exist
+// so its line can still be used in error messages
+func lookupNode(node *yaml.Node, path ...interface{}) *yaml.Node {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	for _, elem := range path {
+		var next *yaml.Node
+
+		switch key := elem.(type) {
+		case string:
+			if node.Kind == yaml.MappingNode {
+				for i := 0; i+1 < len(node.Content); i += 2 {
+					if node.Content[i].Value == key {
+						next = node.Content[i+1]
+						break
+					}
+				}
+			}
+		case int:
+			if node.Kind == yaml.SequenceNode && key < len(node.Content) {
+				next = node.Content[key]
+			}
+		}
+
+		if next == nil {
+			return node
+		}
+		node = next
+	}
+
+	return node
+}
@@ -1,6 +1,13 @@
 package pkg
 
-import "testing"
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
 
 func TestMeta_Source(t *testing.T) {
 	m := Meta{
@@ -27,3 +34,158 @@ func TestMeta_Source(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestReadControlMetadata(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkg_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	ctrlDir := filepath.Join(dir, GoPkgDir)
+	if err := os.MkdirAll(filepath.Join(dir, "cmd", "foo"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(ctrlDir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "cmd", "foo", "main.go"), []byte("package main"), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		errParts []string
+	}{
+		{
+			name: "valid",
+			content: `importpath: github.com/foo/bar
+packages:
+- alias: github.com/foo/bar/cmd/foo
+  main: cmd/foo/main.go
+  binname: foo
+  targets:
+    linux: [amd64, arm64]
+`,
+		},
+		{
+			name: "unknown field",
+			content: `importpath: github.com/foo/bar
+build_dependancies:
+- github.com/foo/baz
+`,
+			errParts: []string{"line 2", "build_dependancies"},
+		},
+		{
+			name: "invalid watch",
+			content: `importpath: github.com/foo/bar
+watch:
+  tag_pattern: ^v(
+  version_mangle:
+  - s/-rc/~rc/
+  - s/-rc/
+`,
+			errParts: []string{"line 3: watch: invalid tag pattern", "line 6: watch: invalid version mangle rule"},
+		},
+		{
+			name: "invalid values",
+			content: `importpath: github.com/foo/bar
+packages:
+- alias: ""
+  main: cmd/foo/main.go
+  binname: foo
+  ldflags: -X main.version={{.Unknown}}
+  env:
+  - GOARM
+  targets:
+    linux: [amd64]
+- alias: github.com/foo/bar/cmd/baz
+  main: cmd/baz/main.go
+  binname: baz
+  targets:
+    plan10: [amd64]
+    linux:
+    - amd64
+    - arm65
+`,
+			errParts: []string{
+				"line 3: packages[0]: alias is empty",
+				"line 6: packages[0]: invalid ldflags",
+				"line 8: packages[0]: invalid env \"GOARM\"",
+				"line 12: packages[1]: main cmd/baz/main.go does not exist",
+				"line 18: packages[1]: unsupported arch arm65 for os linux",
+				"line 15: packages[1]: unsupported os plan10",
+			},
+		},
+		{
+			name: "missing targets",
+			content: `importpath: github.com/foo/bar
+packages:
+- alias: github.com/foo/bar/cmd/baz
+  main: cmd/foo/main.go
+  binname: baz
+  targets: {}
+- alias: github.com/foo/bar/cmd/qux
+  main: cmd/foo/main.go
+  binname: qux
+- alias: github.com/foo/bar/cmd/quux
+  main: cmd/foo/main.go
+  binname: quux
+  targets:
+    linux: []
+`,
+			errParts: []string{
+				"line 6: packages[0]: no targets",
+				"line 7: packages[1]: no targets",
+				"line 14: packages[2]: no arch for os linux",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		if err := ioutil.WriteFile(filepath.Join(ctrlDir, metadataFile), []byte(test.content), 0640); err != nil {
+			t.Fatal(err)
+		}
+
+		m, err := readControlMetadata(ctrlDir)
+		if len(test.errParts) == 0 {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %s", test.name, err)
+			} else if m.ImportPath != "github.com/foo/bar" || len(m.Packages) != 1 {
+				t.Errorf("%s: wrong metadata: %+v", test.name, m)
+			}
+			continue
+		}
+
+		if !errors.Is(err, ErrInvalidMetadata) {
+			t.Errorf("%s: expected ErrInvalidMetadata, got %v", test.name, err)
+			continue
+		}
+		for _, part := range test.errParts {
+			if !strings.Contains(err.Error(), part) {
+				t.Errorf("%s: error %q does not contain %q", test.name, err, part)
+			}
+		}
+	}
+}
+
+func TestBuildVars_Expand(t *testing.T) {
+	vars := NewBuildVars("1.2.0-rc1-2", "abcdef")
+	vars.OS = "linux"
+	vars.Arch = "arm64"
+
+	s, err := vars.Expand("{{.Version}} {{.UpstreamVersion}} {{.Commit}} {{.OS}}/{{.Arch}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "1.2.0-rc1-2 1.2.0-rc1 abcdef linux/arm64" {
+		t.Errorf("wrong expanded value: %s", s)
+	}
+
+	if _, err := vars.Expand("{{.Date}}"); err == nil {
+		t.Error("Expand should have failed")
+	}
+}
@@ -1,19 +1,22 @@
 package pkg
 
 import (
-	"archive/tar"
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/go-pkg-org/gopkg/internal/util"
 	"github.com/rs/zerolog/log"
-	"gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
 )
 
 //go:generate mockgen -destination=../pkg_mock/package_mock.go -package=pkg_mock . File
@@ -38,12 +41,20 @@ const (
 
 // File represent .pkg file content
 type File interface {
+	// Metadata returns the package metadata (package.yaml)
 	Metadata() (Meta, error)
-	Files() map[string][]byte
+	// Files returns the whole package content, indexed by entry path
+	Files() (map[string][]byte, error)
+	// Walk calls fn for each package entry, streaming its content
+	Walk(fn WalkFunc) error
+	// Manifest returns the package manifest (nil for packages built without one)
+	Manifest() (Manifest, error)
 }
 
 type file struct {
 	content map[string][]byte
+	// headers of the package entries (including directories & symlinks)
+	headers map[string]Header
 }
 
 // Entry is a tiny struct to contain data for a specific
@@ -70,6 +81,12 @@ func CreateEntries(path string, pathPrefix string, excludedFiles []string) ([]En
 		}
 
 		if file.IsDir() {
+			// Add the directory itself to keep its mode (even if empty)
+			fileList = append(fileList, Entry{
+				FilePath:    filepath.Join(path, file.Name()),
+				ArchivePath: filepath.Join(pathPrefix, file.Name()),
+			})
+
 			tmp, err := CreateEntries(filepath.Join(path, file.Name()), "", excludedFiles)
 			if err != nil {
 				return nil, err
@@ -93,77 +110,175 @@ func CreateEntries(path string, pathPrefix string, excludedFiles []string) ([]En
 
 // ReadFile reads a package from file and returns content.
 func ReadFile(path string) (File, error) {
-	file, err := ioutil.ReadFile(path)
+	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
+	defer f.Close()
+
+	return Read(f)
+}
+
+// OpenFile opens a package from file without loading its content in memory.
+// The package is read once to make sure it's valid (entries are verified against the manifest),
+// entries are then streamed on demand.
+func OpenFile(path string) (File, error) {
+	p := &diskFile{path: path}
+
+	var format string
+	found := Manifest{}
+	if err := p.Walk(func(h Header, r io.Reader) error {
+		if _, exist := found[h.Name]; exist {
+			return fmt.Errorf("%w: duplicate entry %s", ErrUnsafePath, h.Name)
+		}
+
+		var content bytes.Buffer
+		if IsMetaFile(h.Name) {
+			r = io.TeeReader(r, &content)
+		}
+
+		entry, err := hashEntry(h, r)
+		if err != nil {
+			return err
+		}
+		found[h.Name] = entry
+
+		switch h.Name {
+		case "package.yaml", "package.yml":
+			p.meta = content.Bytes()
+		case ManifestFile:
+			p.manifest = content.Bytes()
+		case FormatFile:
+			format = content.String()
+			// fail early: the remaining entries may not be readable
+			return CheckFormatVersion(format, FormatVersion)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
 
-	buffer := bytes.NewBuffer(file)
-	return Read(buffer)
+	if err := checkPackageFormat(format, p.manifest); err != nil {
+		return nil, err
+	}
+	if err := verifyManifest(p, found); err != nil {
+		return nil, err
+	}
+
+	// the next walks are checked against the verified entries
+	p.entries = found
+
+	return p, nil
 }
 
 // Read reads a package from io.Reader and returns content.
+// The package compression is automatically detected.
 func Read(r io.Reader) (File, error) {
 	result := map[string][]byte{}
+	headers := map[string]Header{}
 
-	tr := tar.NewReader(r)
-	for {
-		header, err := tr.Next()
-		if err == io.EOF {
-			break
+	pr, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer pr.Close()
+
+	if err := pr.Walk(func(h Header, r io.Reader) error {
+		if _, exist := headers[h.Name]; exist {
+			return fmt.Errorf("%w: duplicate entry %s", ErrUnsafePath, h.Name)
+		}
+		headers[h.Name] = h
+		if h.Type != RegularFile {
+			return nil
 		}
 
+		b, err := ioutil.ReadAll(r)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		out := bytes.NewBuffer([]byte{})
-		if _, err := out.ReadFrom(tr); err != nil {
+		result[h.Name] = b
+
+		// fail early: the remaining entries may not be readable
+		if h.Name == FormatFile {
+			return CheckFormatVersion(string(b), FormatVersion)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	p := &file{content: result, headers: headers}
+	if err := checkPackageFormat(string(result[FormatFile]), result[ManifestFile]); err != nil {
+		return nil, err
+	}
+
+	found := Manifest{}
+	for name, h := range headers {
+		entry, err := hashEntry(h, bytes.NewReader(result[name]))
+		if err != nil {
 			return nil, err
 		}
+		found[name] = entry
+	}
+	if err := verifyManifest(p, found); err != nil {
+		return nil, err
+	}
 
-		result[header.Name] = out.Bytes()
+	return p, nil
+}
+
+// verifyManifest make sure the entries found in p match its manifest (if any)
+func verifyManifest(p File, found Manifest) error {
+	m, err := p.Manifest()
+	if err != nil {
+		return err
 	}
-	return &file{content: result}, nil
+	if m == nil {
+		return nil
+	}
+
+	return m.verify(found)
 }
 
-// Write creates a tar file from a set of ArchiveEntries.
-func Write(path string, files []Entry, overwrite bool) error {
+// Write creates a tar file from a set of ArchiveEntries, compressed using given compression.
+// The entries are streamed from disk, sorted and written with the given modification time.
+func Write(path string, files []Entry, overwrite bool, compression Compression, modTime time.Time) error {
 	if !overwrite {
-		if _, err := os.Stat(path); err != nil {
+		if _, err := os.Stat(path); err == nil {
 			return errors.New("failed to create new tar source (file already exist)")
 		}
 	}
 
-	var buffer bytes.Buffer
-	tw := tar.NewWriter(&buffer)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
 
-	for _, file := range files {
-		log.Trace().Str("file-path", file.FilePath).Str("archive-path", file.ArchivePath).Msg("Writing file")
-		fileBody, err := ioutil.ReadFile(file.FilePath)
-		if err != nil {
-			return err
-		}
+	pw, err := NewWriter(f, compression, modTime)
+	if err != nil {
+		return err
+	}
 
-		header := &tar.Header{
-			Name: file.ArchivePath,
-			Mode: 0644,
-			Size: int64(len(fileBody)),
-		}
+	// sort the entries to produce the same package whatever the given order is
+	sorted := make([]Entry, len(files))
+	copy(sorted, files)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return filepath.ToSlash(sorted[i].ArchivePath) < filepath.ToSlash(sorted[j].ArchivePath)
+	})
 
-		if err := tw.WriteHeader(header); err != nil {
-			return err
-		}
-		if _, err := tw.Write(fileBody); err != nil {
+	for _, file := range sorted {
+		if err := pw.WriteEntry(file); err != nil {
 			return err
 		}
 	}
 
-	if err := tw.Close(); err != nil {
+	if err := pw.Close(); err != nil {
 		return err
 	}
 
-	return ioutil.WriteFile(path, buffer.Bytes(), 0644)
+	return f.Close()
 }
 
 // GetFileName return package file name corresponding to given information
@@ -221,23 +336,170 @@ func GetName(importPath string, isSrc bool) string {
 
 // Metadata returns the package metadata
 func (p *file) Metadata() (Meta, error) {
-	var m Meta
-	var c []byte
-
 	if val, ok := p.content["package.yaml"]; ok {
-		c = val
+		return parseMetadata(val)
 	} else if val, ok := p.content["package.yml"]; ok {
-		c = val
+		return parseMetadata(val)
 	}
 
-	if err := yaml.Unmarshal(c, &m); err != nil {
-		return Meta{}, err
+	return Meta{}, fmt.Errorf("%w: missing package.yaml", ErrCorruptPackage)
+}
+
+// Manifest returns the package manifest
+func (p *file) Manifest() (Manifest, error) {
+	if val, ok := p.content[ManifestFile]; ok {
+		return parseManifest(val)
 	}
 
-	return m, nil
+	return nil, nil
+}
+
+// Files returns the package file
+// only regular files are returned
+func (p *file) Files() (map[string][]byte, error) {
+	return p.content, nil
+}
+
+// Walk calls fn for each package entry, in lexical order
+func (p *file) Walk(fn WalkFunc) error {
+	headers := p.headers
+	if headers == nil {
+		headers = map[string]Header{}
+		for name, content := range p.content {
+			headers[name] = Header{Name: name, Size: int64(len(content)), Mode: 0644, Type: RegularFile}
+		}
+	}
+
+	var names []string
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := fn(headers[name], bytes.NewReader(p.content[name])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// diskFile is a package file whose entries are streamed from disk
+type diskFile struct {
+	path     string
+	meta     []byte
+	manifest []byte
+	// entries verified when the package was opened
+	entries Manifest
+}
+
+// Metadata returns the package metadata
+func (p *diskFile) Metadata() (Meta, error) {
+	if p.meta == nil {
+		return Meta{}, fmt.Errorf("%w: missing package.yaml", ErrCorruptPackage)
+	}
+
+	return parseMetadata(p.meta)
+}
+
+// Manifest returns the package manifest
+func (p *diskFile) Manifest() (Manifest, error) {
+	if p.manifest == nil {
+		return nil, nil
+	}
+
+	return parseManifest(p.manifest)
 }
 
 // Files returns the package file
-func (p *file) Files() map[string][]byte {
-	return p.content
+// the whole package is loaded in memory, prefer Walk for big packages
+func (p *diskFile) Files() (map[string][]byte, error) {
+	files := map[string][]byte{}
+	if err := p.Walk(func(h Header, r io.Reader) error {
+		if h.Type != RegularFile {
+			return nil
+		}
+
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+
+		files[h.Name] = b
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// Walk calls fn for each package entry, in archive order
+// since the package is read again from disk, the entries are checked against the ones verified by OpenFile:
+// the content of an entry is verified once fn returns
+func (p *diskFile) Walk(fn WalkFunc) error {
+	f, err := os.Open(p.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pr, err := NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer pr.Close()
+
+	// OpenFile verification
+	if p.entries == nil {
+		return pr.Walk(fn)
+	}
+
+	seen := map[string]bool{}
+	if err := pr.Walk(func(h Header, r io.Reader) error {
+		expected, exist := p.entries[h.Name]
+		if !exist || seen[h.Name] {
+			return fmt.Errorf("%w: unexpected entry %s", ErrManifestMismatch, h.Name)
+		}
+		seen[h.Name] = true
+
+		if h.Type != expected.Type || h.Mode != expected.Mode || h.Linkname != expected.Linkname ||
+			(h.Type == RegularFile && h.Size != expected.Size) {
+			return fmt.Errorf("%w: entry %s has been modified", ErrManifestMismatch, h.Name)
+		}
+
+		hash := sha256.New()
+		if err := fn(h, io.TeeReader(r, hash)); err != nil {
+			return err
+		}
+		// hash the content not consumed by fn
+		if _, err := io.Copy(hash, r); err != nil {
+			return err
+		}
+
+		if h.Type == RegularFile && hex.EncodeToString(hash.Sum(nil)) != expected.SHA256 {
+			return fmt.Errorf("%w: entry %s has been modified", ErrManifestMismatch, h.Name)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for name := range p.entries {
+		if !seen[name] {
+			return fmt.Errorf("%w: missing entry %s", ErrManifestMismatch, name)
+		}
+	}
+
+	return nil
+}
+
+func parseMetadata(b []byte) (Meta, error) {
+	var m Meta
+	if err := yaml.Unmarshal(b, &m); err != nil {
+		return Meta{}, fmt.Errorf("%w: %s", ErrCorruptPackage, err)
+	}
+
+	return m, nil
 }
@@ -1,13 +1,17 @@
 package pkg
 
 import (
+	"bytes"
+	"errors"
 	util "github.com/go-pkg-org/gopkg/internal/util"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func init() {
@@ -88,7 +92,7 @@ func TestWrite(t *testing.T) {
 		{xmlFile.Name(), "test/xmlfile.xml"},
 		{jsonFile.Name(), "jsonfile.json"},
 		{txtFile.Name(), "txtfile.txt"},
-	}, true)
+	}, true, NoCompression, time.Time{})
 
 	if err != nil {
 		t.Errorf("failed to create archive: %s", err)
@@ -119,7 +123,7 @@ func TestRead(t *testing.T) {
 		{xmlFile.Name(), "test/xmlfile.xml"},
 		{jsonFile.Name(), "jsonfile.json"},
 		{txtFile.Name(), "txtfile.txt"},
-	}, true)
+	}, true, NoCompression, time.Time{})
 
 	if err != nil {
 		t.Errorf("failed to create archive: %s", err)
@@ -129,7 +133,10 @@ func TestRead(t *testing.T) {
 	if err != nil {
 		t.Errorf("failed to read the archive: %s", err)
 	}
-	list := p.Files()
+	list, err := p.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	jsonContent := string(list["jsonfile.json"])
 	xmlContent := string(list["test/xmlfile.xml"])
@@ -238,3 +245,65 @@ func TestParseFileName(t *testing.T) {
 		t.Errorf("wrong arch (%s)", arch)
 	}
 }
+
+func TestRead_Corrupt(t *testing.T) {
+	if _, err := Read(strings.NewReader("this is not a package")); !errors.Is(err, ErrCorruptPackage) {
+		t.Errorf("Read should have failed with ErrCorruptPackage (got %v)", err)
+	}
+}
+
+func TestFile_Metadata_Missing(t *testing.T) {
+	f := &file{content: map[string][]byte{"bin/foo": []byte("foo")}}
+
+	if _, err := f.Metadata(); !errors.Is(err, ErrCorruptPackage) {
+		t.Errorf("Metadata should have failed with ErrCorruptPackage (got %v)", err)
+	}
+}
+
+func TestWrite_Reproducible(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gopkg_*")
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	ioutil.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "b.txt"), []byte("b"), 0644)
+	modTime := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	entries := []Entry{
+		{filepath.Join(dir, "b.txt"), "sub/b.txt"},
+		{filepath.Join(dir, "a.txt"), "a.txt"},
+	}
+	if err := Write(filepath.Join(dir, "first.pkg"), entries, true, Gzip, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	// same content, different order & file timestamps
+	os.Chtimes(filepath.Join(dir, "a.txt"), time.Now(), time.Now().Add(time.Hour))
+	entries[0], entries[1] = entries[1], entries[0]
+	if err := Write(filepath.Join(dir, "second.pkg"), entries, true, Gzip, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	first, _ := ioutil.ReadFile(filepath.Join(dir, "first.pkg"))
+	second, _ := ioutil.ReadFile(filepath.Join(dir, "second.pkg"))
+	if !bytes.Equal(first, second) {
+		t.Error("packages with the same content are different")
+	}
+
+	r, err := NewReader(bytes.NewReader(first))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// skip the format entry
+	if _, err := r.tr.Next(); err != nil {
+		t.Fatal(err)
+	}
+	h, err := r.tr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Name != "a.txt" || !h.ModTime.Equal(modTime) || h.Uid != 0 || h.Gid != 0 || h.Uname != "" {
+		t.Errorf("header not normalized: %+v", h)
+	}
+}
@@ -0,0 +1,124 @@
+package pkg
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PatchesDir is the control directory sub directory holding the patches
+const PatchesDir = "patches"
+
+// seriesFile list the patches names in application order
+const seriesFile = "series"
+
+// AppliedFile records the patches applied on the sources, in application order
+// it lives in the patches directory but is not part of the control package
+const AppliedFile = ".applied-patches"
+
+// ReadSeries returns the patches of the control directory located at path, in application order
+// no patches are returned if the control directory has no series
+func ReadSeries(path string) ([]string, error) {
+	f, err := os.Open(filepath.Join(path, GoPkgDir, PatchesDir, seriesFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var series []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// empty lines & comments are ignored
+		name := strings.TrimSpace(scanner.Text())
+		if name == "" || strings.HasPrefix(name, "#") {
+			continue
+		}
+
+		if err := ValidatePatchName(name); err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(PatchPath(path, name)); err != nil {
+			return nil, fmt.Errorf("patch %s listed in series: %w", name, err)
+		}
+
+		series = append(series, name)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return series, nil
+}
+
+// WriteSeries writes the patches series of the control directory located at path
+func WriteSeries(path string, series []string) error {
+	var b strings.Builder
+	for _, name := range series {
+		if err := ValidatePatchName(name); err != nil {
+			return err
+		}
+		b.WriteString(name + "\n")
+	}
+
+	dir := filepath.Join(path, GoPkgDir, PatchesDir)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, seriesFile), []byte(b.String()), 0640)
+}
+
+// ReadApplied returns the patches recorded as applied on the sources of the control directory located at path
+func ReadApplied(path string) ([]string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(path, GoPkgDir, PatchesDir, AppliedFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var applied []string
+	for _, name := range strings.Split(string(b), "\n") {
+		if name = strings.TrimSpace(name); name != "" {
+			applied = append(applied, name)
+		}
+	}
+
+	return applied, nil
+}
+
+// WriteApplied records the patches applied on the sources of the control directory located at path
+func WriteApplied(path string, applied []string) error {
+	dir := filepath.Join(path, GoPkgDir, PatchesDir)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	for _, name := range applied {
+		b.WriteString(name + "\n")
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, AppliedFile), []byte(b.String()), 0640)
+}
+
+// PatchPath returns the path of the given patch of the control directory located at path
+func PatchPath(path, name string) string {
+	return filepath.Join(path, GoPkgDir, PatchesDir, name)
+}
+
+// ValidatePatchName make sure the patch is located directly in the patches directory
+func ValidatePatchName(name string) error {
+	if name == "" || name == seriesFile || name == AppliedFile || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return fmt.Errorf("invalid patch name %q", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package pkg
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSeries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkg_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	// no series
+	series, err := ReadSeries(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 0 {
+		t.Errorf("unexpected series: %v", series)
+	}
+
+	if err := WriteSeries(dir, []string{"fix-build.patch", "../escape.patch"}); err == nil {
+		t.Error("WriteSeries should have failed")
+	}
+
+	if err := WriteSeries(dir, []string{"fix-build.patch", "add-flag.patch"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// patches files are missing
+	if _, err := ReadSeries(dir); err == nil {
+		t.Error("ReadSeries should have failed")
+	}
+
+	for _, name := range []string{"fix-build.patch", "add-flag.patch"} {
+		if err := ioutil.WriteFile(PatchPath(dir, name), []byte(""), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// comments & empty lines are ignored
+	f, err := os.OpenFile(filepath.Join(dir, GoPkgDir, PatchesDir, seriesFile), os.O_APPEND|os.O_WRONLY, 0640)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("\n# disabled.patch\n")
+	f.Close()
+
+	series, err = ReadSeries(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(series, []string{"fix-build.patch", "add-flag.patch"}) {
+		t.Errorf("wrong series: %v", series)
+	}
+}
@@ -0,0 +1,296 @@
+package pkg
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-pkg-org/gopkg/internal/util"
+	"github.com/rs/zerolog/log"
+)
+
+// EntryType represent the type of a package entry
+type EntryType string
+
+const (
+	// RegularFile entries provide content
+	RegularFile EntryType = "file"
+	// Directory entries create (possibly empty) directories
+	Directory EntryType = "dir"
+	// Symlink entries create a symbolic link to Linkname
+	Symlink EntryType = "symlink"
+)
+
+// Header describe a package entry
+type Header struct {
+	// Name is the entry path inside the package
+	Name string
+	// Size is the entry content size
+	Size int64
+	// Mode is the entry permission bits
+	Mode os.FileMode
+	// Type is the entry type
+	Type EntryType
+	// Linkname is the symlink target (only for Symlink entries)
+	Linkname string
+}
+
+// WalkFunc is called for each package entry, r reads the entry content
+type WalkFunc func(h Header, r io.Reader) error
+
+// Reader reads package entries sequentially without loading them in memory
+type Reader struct {
+	dr io.ReadCloser
+	tr *tar.Reader
+}
+
+// NewReader create a reader for the package read from r
+// The package compression is automatically detected.
+func NewReader(r io.Reader) (*Reader, error) {
+	dr, err := decompress(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCorruptPackage, err)
+	}
+
+	return &Reader{dr: dr, tr: tar.NewReader(dr)}, nil
+}
+
+// Next advances to the next entry, io.EOF is returned at the end of the package
+func (r *Reader) Next() (Header, error) {
+	header, err := r.tr.Next()
+	if err == io.EOF {
+		return Header{}, io.EOF
+	}
+	if err != nil {
+		return Header{}, fmt.Errorf("%w: %s", ErrCorruptPackage, err)
+	}
+
+	return toHeader(header)
+}
+
+// toHeader convert a tar header into a package entry header
+func toHeader(header *tar.Header) (Header, error) {
+	h := Header{
+		Name: header.Name,
+		Size: header.Size,
+		Mode: os.FileMode(header.Mode).Perm(),
+	}
+
+	switch header.Typeflag {
+	case tar.TypeReg, tar.TypeRegA:
+		h.Type = RegularFile
+	case tar.TypeDir:
+		h.Type = Directory
+		h.Name = strings.TrimSuffix(h.Name, "/")
+	case tar.TypeSymlink:
+		h.Type = Symlink
+		h.Linkname = header.Linkname
+	default:
+		return Header{}, fmt.Errorf("%w: unsupported entry type %q for %s", ErrCorruptPackage, header.Typeflag, header.Name)
+	}
+
+	return h, nil
+}
+
+// Read reads from the current entry
+func (r *Reader) Read(b []byte) (int, error) {
+	n, err := r.tr.Read(b)
+	if err != nil && err != io.EOF {
+		return n, fmt.Errorf("%w: %s", ErrCorruptPackage, err)
+	}
+
+	return n, err
+}
+
+// Close release the reader resources, the underlying reader is not closed
+func (r *Reader) Close() error {
+	return r.dr.Close()
+}
+
+// Walk calls fn for each remaining entry
+func (r *Reader) Walk(fn WalkFunc) error {
+	for {
+		h, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := fn(h, r); err != nil {
+			return err
+		}
+	}
+}
+
+// Writer writes package entries sequentially without buffering them in memory
+type Writer struct {
+	cw       io.WriteCloser
+	tw       *tar.Writer
+	modTime  time.Time
+	manifest Manifest
+}
+
+// NewWriter create a writer producing a package to w, compressed using given compression
+// the package format version is written straight away
+// every entry is written with the given modification time (unix epoch if zero)
+// and a root ownership so that the same content always produces the same package
+func NewWriter(w io.Writer, compression Compression, modTime time.Time) (*Writer, error) {
+	cw, err := compress(w, compression)
+	if err != nil {
+		return nil, err
+	}
+
+	if modTime.IsZero() {
+		modTime = time.Unix(0, 0)
+	}
+
+	pw := &Writer{
+		cw:       cw,
+		tw:       tar.NewWriter(cw),
+		modTime:  modTime.UTC().Truncate(time.Second),
+		manifest: Manifest{},
+	}
+
+	// the format version is written first so readers can check it before anything else
+	if err := pw.WriteFile(FormatFile, []byte(FormatVersion+"\n")); err != nil {
+		return nil, err
+	}
+
+	return pw, nil
+}
+
+// writeHeader writes h once normalized and records it into the manifest
+func (w *Writer) writeHeader(th *tar.Header) error {
+	th.ModTime = w.modTime
+	th.Uid, th.Gid = 0, 0
+	th.Uname, th.Gname = "", ""
+
+	h, err := toHeader(th)
+	if err != nil {
+		return err
+	}
+	if _, exist := w.manifest[h.Name]; exist {
+		return fmt.Errorf("duplicate entry %s", h.Name)
+	}
+
+	if err := w.tw.WriteHeader(th); err != nil {
+		return err
+	}
+
+	w.manifest[h.Name] = ManifestEntry{Type: h.Type, Size: h.Size, Mode: h.Mode, Linkname: h.Linkname}
+	return nil
+}
+
+// WriteEntry writes the file described by entry, its content is streamed from disk
+// directories & symlinks (not followed) are supported. The modes are normalized
+// (0755 for directories & executables, 0644 otherwise) so they do not depend on the builder umask
+func (w *Writer) WriteEntry(entry Entry) error {
+	log.Trace().Str("file-path", entry.FilePath).Str("archive-path", entry.ArchivePath).Msg("Writing file")
+
+	fi, err := os.Lstat(entry.FilePath)
+	if err != nil {
+		return err
+	}
+
+	name := filepath.ToSlash(entry.ArchivePath)
+
+	switch {
+	case fi.IsDir():
+		return w.writeHeader(&tar.Header{
+			Typeflag: tar.TypeDir,
+			Name:     name + "/",
+			Mode:     0755,
+		})
+	case fi.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(entry.FilePath)
+		if err != nil {
+			return err
+		}
+
+		return w.writeHeader(&tar.Header{
+			Typeflag: tar.TypeSymlink,
+			Name:     name,
+			Linkname: target,
+			Mode:     0777,
+		})
+	case fi.Mode().IsRegular():
+		f, err := os.Open(entry.FilePath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		return w.write(name, fi.Size(), normalizeMode(fi.Mode()), f)
+	default:
+		return fmt.Errorf("unsupported file type for %s", entry.FilePath)
+	}
+}
+
+// normalizeMode returns the mode of a regular file entry
+func normalizeMode(mode os.FileMode) os.FileMode {
+	if mode&0111 != 0 {
+		return 0755
+	}
+
+	return 0644
+}
+
+// WriteFile writes an entry with given content
+func (w *Writer) WriteFile(name string, content []byte) error {
+	log.Trace().Str("archive-path", name).Msg("Writing file")
+
+	return w.write(name, int64(len(content)), 0644, bytes.NewReader(content))
+}
+
+func (w *Writer) write(name string, size int64, mode os.FileMode, r io.Reader) error {
+	if err := w.writeHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     int64(mode),
+		Size:     size,
+	}); err != nil {
+		return err
+	}
+
+	// the file may have changed since stat, make sure we write exactly size bytes
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(w.tw, hash), io.LimitReader(r, size))
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return fmt.Errorf("%s has been truncated while writing", name)
+	}
+
+	entry := w.manifest[name]
+	entry.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	w.manifest[name] = entry
+
+	return nil
+}
+
+// Close writes the package manifest and flush the package, the underlying writer is not closed
+func (w *Writer) Close() error {
+	b, err := util.EncodeYAML(w.manifest)
+	if err != nil {
+		return err
+	}
+	if err := w.write(ManifestFile, int64(len(b)), 0644, bytes.NewReader(b)); err != nil {
+		return err
+	}
+
+	if err := w.tw.Close(); err != nil {
+		return err
+	}
+
+	return w.cw.Close()
+}
@@ -0,0 +1,141 @@
+package pkg
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReaderWriter(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gopkg_*")
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	txtFile, _ := ioutil.TempFile(dir, "*.txt")
+	txtFile.WriteString("This is a txt file")
+	txtFile.Close()
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, Gzip, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteFile("package.yaml", []byte("alias: foo/bar\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteEntry(Entry{FilePath: txtFile.Name(), ArchivePath: "test/txtfile.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	content := map[string]string{}
+	if err := r.Walk(func(h Header, r io.Reader) error {
+		b, err := ioutil.ReadAll(r)
+		if int64(len(b)) != h.Size {
+			t.Errorf("wrong %s size (got %d want %d)", h.Name, len(b), h.Size)
+		}
+		content[h.Name] = string(b)
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if content["package.yaml"] != "alias: foo/bar\n" {
+		t.Errorf("package.yaml could not be read.")
+	}
+	if content["test/txtfile.txt"] != "This is a txt file" {
+		t.Errorf("Txt file could not be read.")
+	}
+}
+
+func TestOpenFile(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gopkg_*")
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	metaFile := filepath.Join(dir, "package.yaml")
+	ioutil.WriteFile(metaFile, []byte("alias: foo/bar\n"), 0640)
+	txtFile, _ := ioutil.TempFile(dir, "*.txt")
+	txtFile.WriteString("This is a txt file")
+	txtFile.Close()
+
+	path := filepath.Join(dir, "out.pkg")
+	if err := Write(path, []Entry{
+		{txtFile.Name(), "txtfile.txt"},
+		{metaFile, "package.yaml"},
+	}, true, Zstd, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := p.Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Alias != "foo/bar" {
+		t.Errorf("wrong alias: %s", m.Alias)
+	}
+
+	var names []string
+	if err := p.Walk(func(h Header, r io.Reader) error {
+		names = append(names, h.Name)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 4 || names[0] != FormatFile || names[1] != "package.yaml" || names[2] != "txtfile.txt" ||
+		names[3] != ManifestFile {
+		t.Errorf("wrong entries: %v", names)
+	}
+
+	files, err := p.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(files["txtfile.txt"]) != "This is a txt file" {
+		t.Errorf("Txt file could not be read.")
+	}
+
+	// Package modified after being opened
+	ioutil.WriteFile(txtFile.Name(), []byte("This is a modified file"), 0640)
+	if err := Write(path, []Entry{
+		{txtFile.Name(), "txtfile.txt"},
+		{metaFile, "package.yaml"},
+	}, true, Zstd, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Walk(func(h Header, r io.Reader) error {
+		return nil
+	}); !errors.Is(err, ErrManifestMismatch) {
+		t.Errorf("Walk should have returned ErrManifestMismatch (got %v)", err)
+	}
+	if _, err := p.Files(); !errors.Is(err, ErrManifestMismatch) {
+		t.Errorf("Files should have returned ErrManifestMismatch (got %v)", err)
+	}
+
+	// Truncated package
+	b, _ := ioutil.ReadFile(path)
+	ioutil.WriteFile(path, b[:len(b)/2], 0640)
+	if _, err := OpenFile(path); err == nil {
+		t.Error("OpenFile should have failed with truncated package")
+	}
+}
@@ -0,0 +1,91 @@
+package pkg
+
+import (
+	"encoding/json"
+	"os/exec"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// knownTargets are the GOOS/GOARCH pairs supported by the Go toolchain (go tool dist list)
+// they are only used when no Go toolchain is available
+var knownTargets = map[string][]string{
+	"aix":       {"ppc64"},
+	"android":   {"386", "amd64", "arm", "arm64"},
+	"darwin":    {"amd64", "arm64"},
+	"dragonfly": {"amd64"},
+	"freebsd":   {"386", "amd64", "arm", "arm64", "riscv64"},
+	"illumos":   {"amd64"},
+	"ios":       {"amd64", "arm64"},
+	"js":        {"wasm"},
+	"linux": {"386", "amd64", "arm", "arm64", "loong64", "mips", "mips64", "mips64le", "mipsle", "ppc64", "ppc64le",
+		"riscv64", "s390x"},
+	"netbsd":  {"386", "amd64", "arm", "arm64"},
+	"openbsd": {"386", "amd64", "arm", "arm64", "ppc64", "riscv64"},
+	"plan9":   {"386", "amd64", "arm"},
+	"solaris": {"amd64"},
+	"wasip1":  {"wasm"},
+	"windows": {"386", "amd64", "arm64"},
+}
+
+var (
+	targetsOnce      sync.Once
+	supportedTargets map[string][]string
+)
+
+// getSupportedTargets returns the GOOS/GOARCH pairs supported by the installed Go toolchain
+// the known targets are used if the toolchain cannot be queried
+func getSupportedTargets() map[string][]string {
+	targetsOnce.Do(func() {
+		targets, err := listTargets()
+		if err != nil {
+			log.Debug().Err(err).Msg("Unable to list the Go toolchain targets, using the known ones")
+			targets = knownTargets
+		}
+
+		supportedTargets = targets
+	})
+
+	return supportedTargets
+}
+
+// listTargets returns the GOOS/GOARCH pairs listed by go tool dist list
+func listTargets() (map[string][]string, error) {
+	b, err := exec.Command("go", "tool", "dist", "list", "-json").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var list []struct {
+		GOOS   string
+		GOARCH string
+	}
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, err
+	}
+
+	targets := map[string][]string{}
+	for _, target := range list {
+		targets[target.GOOS] = append(targets[target.GOOS], target.GOARCH)
+	}
+
+	return targets, nil
+}
+
+// IsSupportedTarget returns true if the given GOOS/GOARCH pair can be built
+func IsSupportedTarget(os, arch string) bool {
+	for _, supportedArch := range getSupportedTargets()[os] {
+		if supportedArch == arch {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsSupportedOS returns true if the given GOOS can be built
+func IsSupportedOS(os string) bool {
+	_, exist := getSupportedTargets()[os]
+	return exist
+}
@@ -0,0 +1,36 @@
+package pkg
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestListTargets(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not installed")
+	}
+
+	targets, err := listTargets()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+	for _, arch := range targets["linux"] {
+		if arch == "amd64" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("linux/amd64 should be listed: %v", targets)
+	}
+}
+
+func TestIsSupportedTarget(t *testing.T) {
+	if !IsSupportedTarget("linux", "amd64") || !IsSupportedOS("darwin") {
+		t.Error("linux/amd64 & darwin should be supported")
+	}
+	if IsSupportedTarget("linux", "arm65") || IsSupportedOS("plan10") {
+		t.Error("linux/arm65 & plan10 should not be supported")
+	}
+}
@@ -0,0 +1,76 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FormatVersion is the version of the packages produced by this version of gopkg
+// the major version is increased on incompatible layout / metadata changes
+const FormatVersion = "1.0"
+
+// FormatFile is the package entry holding the package format version
+// it is always the first entry of the package
+const FormatFile = "format"
+
+// legacyFormatVersion is the version of the packages built before the format was versioned
+// such packages have no format entry & no manifest
+const legacyFormatVersion = "0.0"
+
+// ErrUnsupportedFormat is returned when a package or index has been produced by a newer gopkg
+var ErrUnsupportedFormat = errors.New("unsupported format")
+
+// CheckFormatVersion make sure the given format version is compatible with the supported one
+// i.e it has the same or an older major version
+func CheckFormatVersion(version, supported string) error {
+	major, err := majorVersion(version)
+	if err != nil {
+		return err
+	}
+	supportedMajor, err := majorVersion(supported)
+	if err != nil {
+		return err
+	}
+
+	if major > supportedMajor {
+		return fmt.Errorf("%w: format version %s is not supported (max %s), please upgrade gopkg",
+			ErrUnsupportedFormat, version, supported)
+	}
+
+	return nil
+}
+
+func majorVersion(version string) (int, error) {
+	major, err := strconv.Atoi(strings.SplitN(strings.TrimSpace(version), ".", 2)[0])
+	if err != nil || major < 0 {
+		return 0, fmt.Errorf("%w: invalid format version %q", ErrUnsupportedFormat, version)
+	}
+
+	return major, nil
+}
+
+// checkPackageFormat make sure a package with given format version (empty if missing) can be read
+// and apply the compatibility rules of older versions
+func checkPackageFormat(version string, manifest []byte) error {
+	if version == "" {
+		version = legacyFormatVersion
+	}
+
+	if err := CheckFormatVersion(version, FormatVersion); err != nil {
+		return err
+	}
+
+	major, _ := majorVersion(version)
+	switch major {
+	case 0:
+		// legacy packages have no manifest
+		return nil
+	default:
+		if manifest == nil {
+			return fmt.Errorf("%w: missing %s", ErrCorruptPackage, ManifestFile)
+		}
+		return nil
+	}
+}
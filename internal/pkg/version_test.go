@@ -0,0 +1,61 @@
+package pkg
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestCheckFormatVersion(t *testing.T) {
+	tests := map[string]error{
+		"0.0":     nil,
+		"1.0":     nil,
+		"1.5":     nil,
+		"1.0\n":   nil,
+		"2.0":     ErrUnsupportedFormat,
+		"10":      ErrUnsupportedFormat,
+		"garbage": ErrUnsupportedFormat,
+		"":        ErrUnsupportedFormat,
+	}
+
+	for version, expected := range tests {
+		if err := CheckFormatVersion(version, "1.0"); !errors.Is(err, expected) {
+			t.Errorf("wrong error for %q (got %v want %v)", version, err, expected)
+		}
+	}
+}
+
+func TestCheckPackageFormat(t *testing.T) {
+	tests := map[string]struct {
+		format   string
+		manifest []byte
+		err      error
+	}{
+		"legacy":           {},
+		"current":          {format: FormatVersion, manifest: []byte("{}")},
+		"newer minor":      {format: "1.1", manifest: []byte("{}")},
+		"newer major":      {format: "2.0", manifest: []byte("{}"), err: ErrUnsupportedFormat},
+		"missing manifest": {format: FormatVersion, err: ErrCorruptPackage},
+	}
+
+	for name, test := range tests {
+		if err := checkPackageFormat(test.format, test.manifest); !errors.Is(err, test.err) {
+			t.Errorf("%s: wrong error (got %v want %v)", name, err, test.err)
+		}
+	}
+}
+
+func TestRead_NewerFormat(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: FormatFile, Typeflag: tar.TypeReg, Mode: 0644, Size: 4})
+	tw.Write([]byte("2.0\n"))
+	// an entry type introduced by the newer format
+	tw.WriteHeader(&tar.Header{Name: "pipe", Typeflag: tar.TypeFifo, Mode: 0644})
+	tw.Close()
+
+	if _, err := Read(&buf); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("Read should have returned ErrUnsupportedFormat (got %v)", err)
+	}
+}
@@ -0,0 +1,105 @@
+package pkg
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultTagPattern matches the tags looking like versions, with an optional v prefix
+const DefaultTagPattern = `^v?([0-9].*)$`
+
+// Watch describe how new upstream releases are detected
+type Watch struct {
+	// Remote is the upstream git remote, recorded by gopkg make
+	// (default to the repository resolved from the import path)
+	Remote string `yaml:"remote,omitempty"`
+	// TagPattern is the regexp selecting the release tags (default to DefaultTagPattern)
+	// its first group, if any, is the upstream version
+	TagPattern string `yaml:"tag_pattern,omitempty"`
+	// VersionMangle are sed like rules (s/regexp/replacement/) applied in order on the upstream version
+	// f.e s/-rc/~rc/ so release candidates are lower than the final release
+	VersionMangle []string `yaml:"version_mangle,omitempty"`
+}
+
+// mangleRule is a parsed version mangle rule
+type mangleRule struct {
+	regex       *regexp.Regexp
+	replacement string
+}
+
+// TagVersion returns the upstream version of the given tag
+// false is returned if the tag is not a release one
+func (w Watch) TagVersion(tag string) (string, bool, error) {
+	pattern, err := w.tagPattern()
+	if err != nil {
+		return "", false, err
+	}
+	rules, err := w.mangleRules()
+	if err != nil {
+		return "", false, err
+	}
+
+	match := pattern.FindStringSubmatch(tag)
+	if match == nil {
+		return "", false, nil
+	}
+
+	version := match[0]
+	if len(match) > 1 {
+		version = match[1]
+	}
+
+	for _, rule := range rules {
+		version = rule.regex.ReplaceAllString(version, rule.replacement)
+	}
+
+	return version, true, nil
+}
+
+func (w Watch) tagPattern() (*regexp.Regexp, error) {
+	pattern := w.TagPattern
+	if pattern == "" {
+		pattern = DefaultTagPattern
+	}
+
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tag pattern %q: %s", pattern, err)
+	}
+
+	return regex, nil
+}
+
+func (w Watch) mangleRules() ([]mangleRule, error) {
+	var rules []mangleRule
+	for _, rule := range w.VersionMangle {
+		r, err := parseMangleRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+
+	return rules, nil
+}
+
+// parseMangleRule parse a s/regexp/replacement/ rule, the separator being the character following s
+func parseMangleRule(rule string) (mangleRule, error) {
+	if len(rule) < 2 || rule[0] != 's' {
+		return mangleRule{}, fmt.Errorf("invalid version mangle rule %q (expected s/regexp/replacement/)", rule)
+	}
+
+	sep := rule[1:2]
+	parts := strings.Split(rule[2:], sep)
+	if len(parts) != 3 || parts[2] != "" {
+		return mangleRule{}, fmt.Errorf("invalid version mangle rule %q (expected s/regexp/replacement/)", rule)
+	}
+
+	regex, err := regexp.Compile(parts[0])
+	if err != nil {
+		return mangleRule{}, fmt.Errorf("invalid version mangle rule %q: %s", rule, err)
+	}
+
+	return mangleRule{regex: regex, replacement: parts[1]}, nil
+}
@@ -0,0 +1,37 @@
+package pkg
+
+import "testing"
+
+func TestWatch_TagVersion(t *testing.T) {
+	tests := []struct {
+		watch    Watch
+		tag      string
+		version  string
+		isTagged bool
+	}{
+		{Watch{}, "v1.2.0", "1.2.0", true},
+		{Watch{}, "1.2.0", "1.2.0", true},
+		{Watch{}, "latest", "", false},
+		{Watch{TagPattern: `^release-(.*)$`}, "release-1.2", "1.2", true},
+		{Watch{TagPattern: `^release-(.*)$`}, "v1.2", "", false},
+		{Watch{TagPattern: `^[0-9.]+$`}, "1.2", "1.2", true},
+		{Watch{VersionMangle: []string{"s/-rc/~rc/", "s|_|.|"}}, "v1_2-rc1", "1.2~rc1", true},
+	}
+
+	for _, test := range tests {
+		version, isTagged, err := test.watch.TagVersion(test.tag)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.tag, err)
+		}
+		if version != test.version || isTagged != test.isTagged {
+			t.Errorf("%s: got %s (%v), expected %s (%v)", test.tag, version, isTagged, test.version, test.isTagged)
+		}
+	}
+
+	for _, w := range []Watch{{TagPattern: "("}, {VersionMangle: []string{"s/a/b"}}, {VersionMangle: []string{"x/a/b/"}},
+		{VersionMangle: []string{"s/(/b/"}}} {
+		if _, _, err := w.TagVersion("v1.0.0"); err == nil {
+			t.Errorf("%+v: TagVersion should have failed", w)
+		}
+	}
+}
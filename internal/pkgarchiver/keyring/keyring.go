@@ -3,7 +3,9 @@ package keyring
 import (
 	"bytes"
 	"fmt"
+	"github.com/go-pkg-org/gopkg/internal/pkg"
 	"golang.org/x/crypto/openpgp"
+	"io"
 	"os"
 )
 
@@ -13,7 +15,7 @@ import (
 type Keyring interface {
 	// CheckSignature check signature sig against file
 	// and return corresponding Maintainer if exist, error otherwise
-	CheckSignature(file, sig []byte) (Maintainer, error)
+	CheckSignature(file io.Reader, sig []byte) (Maintainer, error)
 }
 
 // Maintainer represent a maintainer
@@ -25,10 +27,10 @@ type keyring struct {
 	el openpgp.EntityList
 }
 
-func (k *keyring) CheckSignature(file, sig []byte) (Maintainer, error) {
-	who, err := openpgp.CheckDetachedSignature(k.el, bytes.NewReader(file), bytes.NewReader(sig))
+func (k *keyring) CheckSignature(file io.Reader, sig []byte) (Maintainer, error) {
+	who, err := openpgp.CheckDetachedSignature(k.el, file, bytes.NewReader(sig))
 	if err != nil {
-		return Maintainer{}, err
+		return Maintainer{}, fmt.Errorf("%w: %s", pkg.ErrInvalidSignature, err)
 	}
 
 	return Maintainer{Name: getMaintainerName(who)}, nil
@@ -0,0 +1,281 @@
+package pkgarchiver
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/go-pkg-org/gopkg/internal/archive"
+	"github.com/go-pkg-org/gopkg/internal/config"
+	"github.com/go-pkg-org/gopkg/internal/httpclient"
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+	"github.com/go-pkg-org/gopkg/internal/pkgarchiver/keyring"
+	"github.com/go-pkg-org/gopkg/internal/pkgarchiver/storage"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+)
+
+// MirrorFilter select which packages are mirrored
+type MirrorFilter struct {
+	// Prefixes restrict the mirrored packages to the aliases starting with one of them
+	Prefixes []string
+	// OS restrict the mirrored binary packages to given OS
+	OS string
+	// Arch restrict the mirrored binary packages to given arch
+	Arch string
+	// LatestOnly only mirror the latest release of each package
+	LatestOnly bool
+}
+
+// Mirror is the entrypoint of the mirror command
+func Mirror(c *cli.Context) error {
+	httpClient, err := httpclient.New(config.HTTP{
+		Timeout: c.Duration("timeout"),
+		Retries: c.Int("retries"),
+	}, fmt.Sprintf("pkgarchiver/%s", c.App.Version))
+	if err != nil {
+		return err
+	}
+
+	arcClient, err := archive.NewClient(c.String("source"), httpClient, "")
+	if err != nil {
+		return err
+	}
+
+	// Load the source archive keyring (used to validate package signatures)
+	archiveKeyring, err := keyring.FromFile(c.String("archive-keyring"))
+	if err != nil {
+		return fmt.Errorf("error while loading keyring: %s", err)
+	}
+
+	storer, err := newStorage(c)
+	if err != nil {
+		return err
+	}
+
+	filter := MirrorFilter{
+		Prefixes:   c.StringSlice("prefix"),
+		OS:         c.String("os"),
+		Arch:       c.String("arch"),
+		LatestOnly: c.Bool("latest-only"),
+	}
+
+	return mirror(arcClient, archiveKeyring, storer, filter)
+}
+
+// mirror copy the packages matching filter from the source archive to the storage
+// releases already present in the storage index are skipped
+func mirror(arcClient archive.Client, archiveKeyring keyring.Keyring, storer storage.Storage, filter MirrorFilter) error {
+	srcIndex, err := arcClient.GetIndex()
+	if err != nil {
+		return err
+	}
+
+	index, err := storer.GetIndex()
+	if err != nil {
+		return err
+	}
+	if index.Packages == nil {
+		index.Packages = map[string]archive.Package{}
+	}
+
+	// Process packages in a stable order
+	var aliases []string
+	for alias := range srcIndex.Packages {
+		if filter.matchAlias(alias) {
+			aliases = append(aliases, alias)
+		}
+	}
+	sort.Strings(aliases)
+
+	mirrored, skipped := 0, 0
+	for _, alias := range aliases {
+		srcPkg := srcIndex.Packages[alias]
+
+		p, exist := index.Packages[alias]
+		if !exist {
+			p = archive.Package{Description: srcPkg.Description}
+		}
+		if p.Releases == nil {
+			p.Releases = map[string][]archive.Release{}
+		}
+
+		changed := false
+		for version, releases := range srcPkg.Releases {
+			if filter.LatestOnly && version != srcPkg.LatestRelease {
+				continue
+			}
+
+			for _, release := range releases {
+				if !filter.matchRelease(release) {
+					continue
+				}
+
+				if hasRelease(p.Releases[version], release) {
+					skipped++
+					continue
+				}
+
+				log.Debug().Str("alias", alias).Str("version", version).Str("path", release.Path).Msg("Mirroring package")
+				if err := mirrorRelease(arcClient, archiveKeyring, storer, release); err != nil {
+					return fmt.Errorf("error while mirroring %s: %w", release.Path, err)
+				}
+
+				p.Releases[version] = append(removeRelease(p.Releases[version], release), release)
+				changed = true
+				mirrored++
+			}
+		}
+
+		if !changed {
+			continue
+		}
+
+		if _, ok := p.Releases[srcPkg.LatestRelease]; ok {
+			p.LatestRelease = srcPkg.LatestRelease
+		}
+		p.Description = srcPkg.Description
+
+		// Update the index after each package so interrupted runs keep their progress
+		index.Packages[alias] = p
+		if err := storer.UpdateIndex(index); err != nil {
+			return err
+		}
+	}
+
+	log.Info().Int("mirrored", mirrored).Int("skipped", skipped).Msg("Successfully mirrored archive")
+
+	return nil
+}
+
+// mirrorRelease download, validate & store given release along with its signature
+// the package is spooled into a temporary file to avoid holding it in memory
+func mirrorRelease(arcClient archive.Client, archiveKeyring keyring.Keyring, storer storage.Storage, release archive.Release) error {
+	// the path comes from the remote index
+	if err := pkg.ValidatePath(release.Path); err != nil {
+		return fmt.Errorf("invalid release path: %w", err)
+	}
+
+	sig, err := readFile(arcClient, release.Path+".asc")
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile("", "pkgarchiver-*."+pkg.FileExt)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	r, err := arcClient.Open(release.Path)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	r.Close()
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := archiveKeyring.CheckSignature(f, sig); err != nil {
+		return fmt.Errorf("error while checking package signature: %w", err)
+	}
+
+	if release.Checksum != "" {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		checksum, err := archive.Checksum(f)
+		if err != nil {
+			return err
+		}
+		if checksum != release.Checksum {
+			return archive.ErrChecksumMismatch
+		}
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := storer.Upload(f, release.Path); err != nil {
+		return fmt.Errorf("error while uploading package: %s", err)
+	}
+
+	if err := storer.Upload(bytes.NewReader(sig), release.Path+".asc"); err != nil {
+		return fmt.Errorf("error while uploading package signature: %s", err)
+	}
+
+	return nil
+}
+
+// readFile returns the content of given archive file
+func readFile(arcClient archive.Client, path string) ([]byte, error) {
+	r, err := arcClient.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
+
+func (f *MirrorFilter) matchAlias(alias string) bool {
+	if len(f.Prefixes) == 0 {
+		return true
+	}
+
+	for _, prefix := range f.Prefixes {
+		if strings.HasPrefix(alias, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (f *MirrorFilter) matchRelease(release archive.Release) bool {
+	// source packages are not bound to any target
+	if release.OS == "" && release.Arch == "" {
+		return true
+	}
+
+	if f.OS != "" && release.OS != f.OS {
+		return false
+	}
+	if f.Arch != "" && release.Arch != f.Arch {
+		return false
+	}
+
+	return true
+}
+
+// hasRelease determinate if given release is already mirrored
+func hasRelease(releases []archive.Release, release archive.Release) bool {
+	for _, r := range releases {
+		if r.Path == release.Path && r.Checksum == release.Checksum {
+			return true
+		}
+	}
+
+	return false
+}
+
+// removeRelease remove any release stored at the same path than given one
+func removeRelease(releases []archive.Release, release archive.Release) []archive.Release {
+	var result []archive.Release
+	for _, r := range releases {
+		if r.Path != release.Path {
+			result = append(result, r)
+		}
+	}
+
+	return result
+}
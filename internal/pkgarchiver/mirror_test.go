@@ -0,0 +1,164 @@
+package pkgarchiver
+
+import (
+	"bytes"
+	"errors"
+	"github.com/go-pkg-org/gopkg/internal/archive"
+	"github.com/go-pkg-org/gopkg/internal/archive_mock"
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+	"github.com/go-pkg-org/gopkg/internal/pkgarchiver/keyring"
+	"github.com/go-pkg-org/gopkg/internal/pkgarchiver/keyring_mock"
+	"github.com/go-pkg-org/gopkg/internal/pkgarchiver/storage_mock"
+	"github.com/golang/mock/gomock"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestMirror(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	linuxRelease := archive.Release{OS: "linux", Arch: "amd64", Path: "foo/bar/foo-bar_1.0.0-2_linux_amd64.pkg",
+		Checksum: checksum("linux")}
+	darwinRelease := archive.Release{OS: "darwin", Arch: "amd64", Path: "foo/bar/foo-bar_1.0.0-2_darwin_amd64.pkg",
+		Checksum: checksum("darwin")}
+	oldRelease := archive.Release{OS: "linux", Arch: "amd64", Path: "foo/bar/foo-bar_1.0.0-1_linux_amd64.pkg"}
+
+	arc := archive_mock.NewMockClient(ctrl)
+	arc.EXPECT().GetIndex().Return(archive.Index{Packages: map[string]archive.Package{
+		"foo/bar": {
+			Releases: map[string][]archive.Release{
+				"1.0.0-1": {oldRelease},
+				"1.0.0-2": {linuxRelease, darwinRelease},
+			},
+			LatestRelease: "1.0.0-2",
+		},
+		"other/pkg": {
+			Releases:      map[string][]archive.Release{"1.0.0-1": {{Path: "other/pkg/other-pkg-src_1.0.0-1.pkg"}}},
+			LatestRelease: "1.0.0-1",
+		},
+	}}, nil)
+	arc.EXPECT().Open(linuxRelease.Path).Return(ioutil.NopCloser(strings.NewReader("linux")), nil)
+	arc.EXPECT().Open(linuxRelease.Path+".asc").Return(ioutil.NopCloser(strings.NewReader("sig")), nil)
+
+	k := keyring_mock.NewMockKeyring(ctrl)
+	k.EXPECT().CheckSignature(gomock.Any(), []byte("sig")).DoAndReturn(func(r io.Reader, sig []byte) (keyring.Maintainer, error) {
+		if b, _ := ioutil.ReadAll(r); string(b) != "linux" {
+			t.Errorf("wrong signed content: %s", b)
+		}
+		return keyring.Maintainer{}, nil
+	})
+
+	// old release is already mirrored
+	storer := storage_mock.NewMockStorage(ctrl)
+	storer.EXPECT().GetIndex().Return(archive.Index{Packages: map[string]archive.Package{
+		"foo/bar": {
+			Releases:      map[string][]archive.Release{"1.0.0-1": {oldRelease}},
+			LatestRelease: "1.0.0-1",
+		},
+	}}, nil)
+	uploads := map[string]string{}
+	storer.EXPECT().Upload(gomock.Any(), gomock.Any()).Times(2).DoAndReturn(func(r io.Reader, path string) error {
+		b, _ := ioutil.ReadAll(r)
+		uploads[path] = string(b)
+		return nil
+	})
+	storer.EXPECT().UpdateIndex(archive.Index{Packages: map[string]archive.Package{
+		"foo/bar": {
+			Releases: map[string][]archive.Release{
+				"1.0.0-1": {oldRelease},
+				"1.0.0-2": {linuxRelease},
+			},
+			LatestRelease: "1.0.0-2",
+		},
+	}}).Return(nil)
+
+	filter := MirrorFilter{Prefixes: []string{"foo/"}, OS: "linux", Arch: "amd64"}
+	if err := mirror(arc, k, storer, filter); err != nil {
+		t.Error(err)
+	}
+
+	if uploads[linuxRelease.Path] != "linux" || uploads[linuxRelease.Path+".asc"] != "sig" {
+		t.Errorf("wrong uploads: %v", uploads)
+	}
+}
+
+func TestMirror_ChecksumMismatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	release := archive.Release{Path: "foo/foo-src_1.0.0-1.pkg", Checksum: checksum("foo")}
+
+	arc := archive_mock.NewMockClient(ctrl)
+	arc.EXPECT().GetIndex().Return(archive.Index{Packages: map[string]archive.Package{
+		"foo": {
+			Releases:      map[string][]archive.Release{"1.0.0-1": {release}},
+			LatestRelease: "1.0.0-1",
+		},
+	}}, nil)
+	arc.EXPECT().Open(release.Path).Return(ioutil.NopCloser(strings.NewReader("corrupted")), nil)
+	arc.EXPECT().Open(release.Path+".asc").Return(ioutil.NopCloser(strings.NewReader("sig")), nil)
+
+	k := keyring_mock.NewMockKeyring(ctrl)
+	k.EXPECT().CheckSignature(gomock.Any(), []byte("sig")).Return(keyring.Maintainer{}, nil)
+
+	storer := storage_mock.NewMockStorage(ctrl)
+	storer.EXPECT().GetIndex().Return(archive.Index{}, nil)
+
+	if err := mirror(arc, k, storer, MirrorFilter{}); err == nil {
+		t.Error("mirror should have failed with checksum mismatch")
+	}
+}
+
+func TestMirror_HostileIndex(t *testing.T) {
+	for _, path := range []string{"../../evil.pkg", "/tmp/evil.pkg", `foo\..\..\evil.pkg`, "foo/../../evil.pkg"} {
+		t.Run(path, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			arc := archive_mock.NewMockClient(ctrl)
+			arc.EXPECT().GetIndex().Return(archive.Index{Packages: map[string]archive.Package{
+				"foo": {
+					Releases:      map[string][]archive.Release{"1.0.0-1": {{Path: path}}},
+					LatestRelease: "1.0.0-1",
+				},
+			}}, nil)
+
+			// nothing should be fetched, checked or stored
+			k := keyring_mock.NewMockKeyring(ctrl)
+			storer := storage_mock.NewMockStorage(ctrl)
+			storer.EXPECT().GetIndex().Return(archive.Index{}, nil)
+
+			if err := mirror(arc, k, storer, MirrorFilter{}); !errors.Is(err, pkg.ErrUnsafePath) {
+				t.Errorf("mirror should have returned ErrUnsafePath (got %v)", err)
+			}
+		})
+	}
+}
+
+func TestMirrorFilter(t *testing.T) {
+	f := MirrorFilter{Prefixes: []string{"github.com/creekorful"}, OS: "linux"}
+
+	if !f.matchAlias("github.com/creekorful/mvnparser") {
+		t.Error("alias should have matched")
+	}
+	if f.matchAlias("github.com/other/mvnparser") {
+		t.Error("alias should not have matched")
+	}
+	if !f.matchRelease(archive.Release{}) {
+		t.Error("source release should have matched")
+	}
+	if !f.matchRelease(archive.Release{OS: "linux", Arch: "arm64"}) {
+		t.Error("linux release should have matched")
+	}
+	if f.matchRelease(archive.Release{OS: "darwin", Arch: "amd64"}) {
+		t.Error("darwin release should not have matched")
+	}
+}
+
+func checksum(content string) string {
+	sum, _ := archive.Checksum(bytes.NewReader([]byte(content)))
+	return sum
+}
@@ -8,8 +8,10 @@ import (
 	"io/ioutil"
 	"mime/multipart"
 	"net/http"
+	"os"
 
 	"github.com/go-pkg-org/gopkg/internal/archive"
+	"github.com/go-pkg-org/gopkg/internal/lint"
 	"github.com/go-pkg-org/gopkg/internal/pkg"
 	"github.com/go-pkg-org/gopkg/internal/pkgarchiver/keyring"
 	"github.com/go-pkg-org/gopkg/internal/pkgarchiver/signing"
@@ -36,8 +38,7 @@ func Execute(c *cli.Context) error {
 	}
 
 	// Open the storage session
-	storer, err := storage.NewFTPStorage(c.String("ftp-host"), c.String("ftp-user"),
-		c.String("ftp-pass"), c.String("ftp-dir"))
+	storer, err := newStorage(c)
 	if err != nil {
 		return err
 	}
@@ -60,12 +61,14 @@ func Execute(c *cli.Context) error {
 func handleUpload(maintainerKeyring keyring.Keyring, signer signing.Signer,
 	index archive.Index, storer storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		pkgFile, header, err := readFormFile(r, "package")
+		// Spool the package to disk since it may be huge
+		pkgPath, header, err := saveFormFile(r, "package")
 		if err != nil {
 			log.Err(err).Msg("error while reading package")
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
+		defer os.Remove(pkgPath)
 
 		pkgFileAsc, _, err := readFormFile(r, "packageAsc")
 		if err != nil {
@@ -77,19 +80,36 @@ func handleUpload(maintainerKeyring keyring.Keyring, signer signing.Signer,
 		log.Info().Str("package", header.Filename).Msg("Handling package")
 
 		// Validate signature
-		maintainer, err := maintainerKeyring.CheckSignature(pkgFile, pkgFileAsc)
+		maintainer, err := checkSignature(maintainerKeyring, pkgPath, pkgFileAsc)
 		if err != nil {
 			log.Err(err).Msg("error while checking package signature")
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
+		// Apply the same rules as `gopkg lint`
+		issues, err := lintPackage(pkgPath, header.Filename)
+		if err != nil {
+			log.Err(err).Msg("error while checking package")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(w, err)
+			return
+		}
+		if lint.HasErrors(issues) {
+			log.Warn().Str("package", header.Filename).Msg("Rejected package with lint errors")
+			w.WriteHeader(http.StatusBadRequest)
+			for _, issue := range issues {
+				fmt.Fprintln(w, issue)
+			}
+			return
+		}
+
 		log.Info().
 			Str("package", header.Filename).
 			Str("maintainer", maintainer.Name).
 			Msg("Accepted package")
 
-		if err := handleAcceptedPackage(signer, storer, index, pkgFile); err != nil {
+		if err := handleAcceptedPackage(signer, storer, index, pkgPath); err != nil {
 			log.Err(err).Msg("error while uploading package")
 			w.WriteHeader(http.StatusInternalServerError)
 			return
@@ -101,9 +121,9 @@ func handleAcceptedPackage(
 	signer signing.Signer,
 	storer storage.Storage,
 	index archive.Index,
-	pkgBytes []byte) error {
+	pkgPath string) error {
 	// Read package
-	pkgContent, err := pkg.Read(bytes.NewReader(pkgBytes))
+	pkgContent, err := pkg.OpenFile(pkgPath)
 	if err != nil {
 		return err
 	}
@@ -135,19 +155,37 @@ func handleAcceptedPackage(
 		return err
 	}
 
+	f, err := os.Open(pkgPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
 	// Create the package signature
-	sig, err := signer.Sign(pkgBytes)
+	sig, err := signer.Sign(f)
 	if err != nil {
 		return fmt.Errorf("error while signing package: %s", err)
 	}
 
+	// Compute the package checksum
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	checksum, err := archive.Checksum(f)
+	if err != nil {
+		return err
+	}
+
 	// Upload the package
-	if err := storer.Upload(pkgBytes, fmt.Sprintf("%s/%s", meta.Alias, fileName)); err != nil {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := storer.Upload(f, fmt.Sprintf("%s/%s", meta.Alias, fileName)); err != nil {
 		return fmt.Errorf("error while uploading package: %s", err)
 	}
 
 	// Upload the signature
-	if err := storer.Upload(sig, fmt.Sprintf("%s/%s.asc", meta.Alias, fileName)); err != nil {
+	if err := storer.Upload(bytes.NewReader(sig), fmt.Sprintf("%s/%s.asc", meta.Alias, fileName)); err != nil {
 		return fmt.Errorf("error while uploading package signature: %s", err)
 	}
 
@@ -165,9 +203,10 @@ func handleAcceptedPackage(
 
 	// Update the package status
 	p.Releases[meta.ReleaseVersion] = append(index.Packages[meta.Alias].Releases[meta.ReleaseVersion], archive.Release{
-		OS:   meta.TargetOS,
-		Arch: meta.TargetArch,
-		Path: fmt.Sprintf("%s/%s", meta.Alias, fileName),
+		OS:       meta.TargetOS,
+		Arch:     meta.TargetArch,
+		Path:     fmt.Sprintf("%s/%s", meta.Alias, fileName),
+		Checksum: checksum,
 	})
 	p.LatestRelease = meta.ReleaseVersion
 
@@ -184,6 +223,74 @@ func handleAcceptedPackage(
 	return nil
 }
 
+// lintPackage apply the lint rules on the package stored at pkgPath
+func lintPackage(pkgPath, fileName string) ([]lint.Issue, error) {
+	f, err := pkg.OpenFile(pkgPath)
+	if err != nil {
+		return nil, err
+	}
+
+	issues, err := lint.File(f, fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, issue := range issues {
+		log.Debug().Str("package", fileName).Str("rule", issue.Rule).
+			Str("severity", string(issue.Severity)).Msg(issue.Message)
+	}
+
+	return issues, nil
+}
+
+// newStorage open the archive storage configured on the command line
+func newStorage(c *cli.Context) (storage.Storage, error) {
+	if dir := c.String("storage-dir"); dir != "" {
+		return storage.NewDirStorage(dir)
+	}
+
+	return storage.NewFTPStorage(c.String("ftp-host"), c.String("ftp-user"),
+		c.String("ftp-pass"), c.String("ftp-dir"))
+}
+
+// checkSignature check the signature of the package stored at pkgPath
+func checkSignature(maintainerKeyring keyring.Keyring, pkgPath string, sig []byte) (keyring.Maintainer, error) {
+	f, err := os.Open(pkgPath)
+	if err != nil {
+		return keyring.Maintainer{}, err
+	}
+	defer f.Close()
+
+	return maintainerKeyring.CheckSignature(f, sig)
+}
+
+// saveFormFile save the given form file into a temporary file and returns its path
+func saveFormFile(r *http.Request, paramName string) (string, *multipart.FileHeader, error) {
+	f, header, err := r.FormFile(paramName)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	tmp, err := ioutil.TempFile("", "pkgarchiver-*."+pkg.FileExt)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if _, err := io.Copy(tmp, f); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", nil, err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", nil, err
+	}
+
+	return tmp.Name(), header, nil
+}
+
 func readFormFile(r *http.Request, paramName string) ([]byte, *multipart.FileHeader, error) {
 	f, header, err := r.FormFile(paramName)
 	if err != nil {
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"golang.org/x/crypto/openpgp"
 	"golang.org/x/crypto/openpgp/packet"
+	"io"
 	"os"
 )
 
@@ -12,16 +13,16 @@ import (
 // Signer is something that can cryptographically sign data
 type Signer interface {
 	// Sign given message using configured key
-	Sign(message []byte) ([]byte, error)
+	Sign(message io.Reader) ([]byte, error)
 }
 
 type signer struct {
 	e *openpgp.Entity
 }
 
-func (s *signer) Sign(message []byte) ([]byte, error) {
+func (s *signer) Sign(message io.Reader) ([]byte, error) {
 	var buf bytes.Buffer
-	if err := openpgp.DetachSign(&buf, s.e, bytes.NewReader(message), nil); err != nil {
+	if err := openpgp.DetachSign(&buf, s.e, message, nil); err != nil {
 		return nil, err
 	}
 
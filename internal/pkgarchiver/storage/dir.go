@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"bytes"
+	"github.com/go-pkg-org/gopkg/internal/archive"
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+type dirStorage struct {
+	dir string
+}
+
+func (d *dirStorage) GetIndex() (archive.Index, error) {
+	f, err := os.Open(filepath.Join(d.dir, "index.json"))
+	if err != nil {
+		// No index exist at the time, create new one
+		if os.IsNotExist(err) {
+			return archive.NewIndex(), nil
+		}
+
+		return archive.Index{}, err
+	}
+	defer f.Close()
+
+	index, err := archive.DecodeIndex(f)
+	if err != nil {
+		return archive.Index{}, err
+	}
+
+	return index, nil
+}
+
+func (d *dirStorage) UpdateIndex(index archive.Index) error {
+	var b bytes.Buffer
+	if err := archive.EncodeIndex(&b, index); err != nil {
+		return err
+	}
+
+	return d.Upload(&b, "index.json")
+}
+
+func (d *dirStorage) Upload(file io.Reader, path string) error {
+	// never write outside of the storage directory
+	if err := pkg.ValidatePath(path); err != nil {
+		return err
+	}
+
+	target := filepath.Join(d.dir, filepath.FromSlash(path))
+
+	// first of all create any missing directories
+	if err := os.MkdirAll(filepath.Dir(target), 0750); err != nil {
+		return err
+	}
+
+	// write to a temporary file first so readers never see partial files
+	tmp, err := ioutil.TempFile(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, file); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), target)
+}
+
+// NewDirStorage create a brand new storage using a local directory as backend
+// the directory can be served as-is or used directly as a local archive
+func NewDirStorage(dir string) (Storage, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, err
+	}
+
+	return &dirStorage{dir: dir}, nil
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"errors"
+	"github.com/go-pkg-org/gopkg/internal/archive"
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDirStorage(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gopkg_*")
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	s, err := NewDirStorage(filepath.Join(dir, "archive"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := s.GetIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Packages) != 0 {
+		t.Errorf("wrong number of packages: %d", len(index.Packages))
+	}
+
+	if err := s.Upload(strings.NewReader("hello"), "foo/bar/foo-bar_1.0.0-1_linux_amd64.pkg"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "archive", "foo", "bar", "foo-bar_1.0.0-1_linux_amd64.pkg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "hello" {
+		t.Errorf("wrong file content: %s", b)
+	}
+
+	index.Packages["foo/bar"] = archive.Package{LatestRelease: "1.0.0-1"}
+	if err := s.UpdateIndex(index); err != nil {
+		t.Fatal(err)
+	}
+
+	index, err = s.GetIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.Packages["foo/bar"].LatestRelease != "1.0.0-1" {
+		t.Errorf("wrong index: %+v", index)
+	}
+}
+
+func TestDirStorage_UploadOutside(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gopkg_*")
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	s, err := NewDirStorage(filepath.Join(dir, "archive"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"../evil.pkg", "foo/../../evil.pkg", filepath.Join(dir, "evil.pkg"), `..\evil.pkg`} {
+		if err := s.Upload(strings.NewReader("evil"), path); !errors.Is(err, pkg.ErrUnsafePath) {
+			t.Errorf("Upload(%s) should have returned ErrUnsafePath (got %v)", path, err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "evil.pkg")); err == nil {
+		t.Error("file has been written outside of the storage directory")
+	}
+}
@@ -2,9 +2,10 @@ package storage
 
 import (
 	"bytes"
-	"encoding/json"
 	"github.com/go-pkg-org/gopkg/internal/archive"
+	"github.com/go-pkg-org/gopkg/internal/pkg"
 	"github.com/jlaffaye/ftp"
+	"io"
 	"path/filepath"
 	"strings"
 	"time"
@@ -19,14 +20,14 @@ func (f *ftpStorage) GetIndex() (archive.Index, error) {
 	if err != nil {
 		// No index exist at the time, create new one
 		if err.Error() == "550 Can't open index.json: No such file or directory" {
-			return archive.Index{Packages: map[string]archive.Package{}}, nil
+			return archive.NewIndex(), nil
 		}
 
 		return archive.Index{}, err
 	}
 
-	var index archive.Index
-	if err := json.NewDecoder(resp).Decode(&index); err != nil {
+	index, err := archive.DecodeIndex(resp)
+	if err != nil {
 		return archive.Index{}, err
 	}
 	resp.Close() // Need to be close or we are failing the FTP client
@@ -35,21 +36,25 @@ func (f *ftpStorage) GetIndex() (archive.Index, error) {
 }
 
 func (f *ftpStorage) UpdateIndex(index archive.Index) error {
-	b, err := json.Marshal(index)
-	if err != nil {
+	var b bytes.Buffer
+	if err := archive.EncodeIndex(&b, index); err != nil {
 		return err
 	}
 
-	return f.Upload(b, "index.json")
+	return f.Upload(&b, "index.json")
 }
 
-func (f *ftpStorage) Upload(file []byte, path string) error {
+func (f *ftpStorage) Upload(file io.Reader, path string) error {
+	if err := pkg.ValidatePath(path); err != nil {
+		return err
+	}
+
 	// first of all create any missing directories
 	if err := f.makeMissingDirectories(filepath.Dir(path)); err != nil {
 		return err
 	}
 
-	if err := f.conn.Stor(path, bytes.NewReader(file)); err != nil {
+	if err := f.conn.Stor(path, file); err != nil {
 		return err
 	}
 
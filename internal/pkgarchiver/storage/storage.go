@@ -2,6 +2,7 @@ package storage
 
 import (
 	"github.com/go-pkg-org/gopkg/internal/archive"
+	"io"
 )
 
 //go:generate mockgen -destination=../storage_mock/storage_mock.go -package=storage_mock . Storage
@@ -13,5 +14,5 @@ type Storage interface {
 	// UpdateIndex update the index with given one
 	UpdateIndex(index archive.Index) error
 	// Upload upload given file to the storage
-	Upload(file []byte, path string) error
+	Upload(file io.Reader, path string) error
 }
@@ -1,10 +1,15 @@
 package sign
 
 import (
+	"bufio"
+	"bytes"
 	"errors"
+	"fmt"
 	"github.com/go-pkg-org/gopkg/internal/config"
+	"github.com/go-pkg-org/gopkg/internal/pkg"
 	"github.com/rs/zerolog/log"
 	"os/exec"
+	"strings"
 )
 
 // Sign sign given package
@@ -45,3 +50,45 @@ func signPackage(pkgPath, ascPath, key, keyring string) error {
 
 	return cmd.Run()
 }
+
+// Verify check the detached signature ascPath of given package using the user keyring
+// the signer identity is returned
+func Verify(pkgPath, ascPath string) (string, error) {
+	cmd := exec.Command("gpg", "--status-fd", "1", "--verify", ascPath, pkgPath)
+	out, err := cmd.Output()
+
+	signer := parseGoodSig(out)
+	if err != nil || signer == "" {
+		return "", fmt.Errorf("%w: %s", pkg.ErrInvalidSignature, gpgError(err))
+	}
+
+	return signer, nil
+}
+
+// parseGoodSig extract the signer from gpg status output
+// i.e `[GNUPG:] GOODSIG <key-id> <user-id>`
+func parseGoodSig(status []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(status))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), " ", 4)
+		if len(parts) == 4 && parts[0] == "[GNUPG:]" && parts[1] == "GOODSIG" {
+			return parts[3]
+		}
+	}
+
+	return ""
+}
+
+// gpgError returns the last line of the gpg error output if any
+func gpgError(err error) string {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		lines := strings.Split(strings.TrimSpace(string(exitErr.Stderr)), "\n")
+		return lines[len(lines)-1]
+	}
+	if err != nil {
+		return err.Error()
+	}
+
+	return "missing good signature"
+}
@@ -4,14 +4,17 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/rs/zerolog/log"
+	"io"
 	"io/ioutil"
 	"mime/multipart"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
-// Upload upload given package to given archive
-func Upload(pkgPath, archive string) error {
+// Upload upload given package to given archive using given HTTP client
+func Upload(pkgPath, archive string, httpClient *http.Client) error {
 	log.Info().Str("package", pkgPath).Str("archive", archive).Msg("Uploading package")
 
 	pkgAscPath := fmt.Sprintf("%s.asc", pkgPath)
@@ -25,7 +28,7 @@ func Upload(pkgPath, archive string) error {
 	}
 
 	// Upload the package
-	if err := uploadPackage(pkgPath, pkgAscPath, fmt.Sprintf("%s/packages", archive)); err != nil {
+	if err := uploadPackage(httpClient, pkgPath, pkgAscPath, fmt.Sprintf("%s/packages", strings.TrimSuffix(archive, "/"))); err != nil {
 		log.Err(err).Msg("error while uploading package")
 		return err
 	}
@@ -35,58 +38,78 @@ func Upload(pkgPath, archive string) error {
 	return nil
 }
 
-func uploadPackage(pkgPath, pkgAscPath, where string) error {
-	body := new(bytes.Buffer)
-	writer := multipart.NewWriter(body)
+func uploadPackage(httpClient *http.Client, pkgPath, pkgAscPath, where string) error {
+	boundary := multipart.NewWriter(nil).Boundary()
 
-	if err := addFileFormParam(writer, "package", pkgPath); err != nil {
-		return err
-	}
-	if err := addFileFormParam(writer, "packageAsc", pkgAscPath); err != nil {
-		return err
-	}
-	if err := writer.Close(); err != nil {
-		return err
+	// The body is streamed from disk, GetBody allow the request to be retried when rate limited
+	getBody := func() (io.ReadCloser, error) {
+		return newMultipartBody(boundary, pkgPath, pkgAscPath), nil
 	}
+	body, _ := getBody()
 
 	req, err := http.NewRequest("POST", where, body)
 	if err != nil {
 		return err
 	}
-	req.Header.Add("Content-Type", writer.FormDataContentType())
+	req.GetBody = getBody
+	req.Header.Add("Content-Type", "multipart/form-data; boundary="+boundary)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
+		// the archive may explain why the package has been rejected (lint errors)
+		reason, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		if len(bytes.TrimSpace(reason)) > 0 {
+			return fmt.Errorf("error while uploading file: %s\n%s", resp.Status, bytes.TrimSpace(reason))
+		}
 		return fmt.Errorf("error while uploading file: %s", resp.Status)
 	}
 
 	return nil
 }
 
+// newMultipartBody returns a reader streaming the upload form
+func newMultipartBody(boundary, pkgPath, pkgAscPath string) io.ReadCloser {
+	pr, pw := io.Pipe()
+
+	go func() {
+		writer := multipart.NewWriter(pw)
+		if err := writer.SetBoundary(boundary); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+
+		if err := addFileFormParam(writer, "package", pkgPath); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		if err := addFileFormParam(writer, "packageAsc", pkgAscPath); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+
+		pw.CloseWithError(writer.Close())
+	}()
+
+	return pr
+}
+
 func addFileFormParam(w *multipart.Writer, param, filePath string) error {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return err
 	}
-	fileContents, err := ioutil.ReadAll(file)
-	if err != nil {
-		return err
-	}
-	fi, err := file.Stat()
-	if err != nil {
-		return err
-	}
-	file.Close()
+	defer file.Close()
 
-	part, err := w.CreateFormFile(param, fi.Name())
+	part, err := w.CreateFormFile(param, filepath.Base(filePath))
 	if err != nil {
 		return err
 	}
-	if _, err := part.Write(fileContents); err != nil {
+	if _, err := io.Copy(part, file); err != nil {
 		return err
 	}
 
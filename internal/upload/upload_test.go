@@ -0,0 +1,48 @@
+package upload
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUpload(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gopkg_*")
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	pkgPath := filepath.Join(dir, "foo-bar_1.0.0-1_linux_amd64.pkg")
+	ioutil.WriteFile(pkgPath, []byte("package"), 0640)
+	ioutil.WriteFile(pkgPath+".asc", []byte("signature"), 0640)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/packages" {
+			t.Errorf("wrong path: %s", r.URL.Path)
+		}
+
+		f, header, err := r.FormFile("package")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, _ := ioutil.ReadAll(f); string(b) != "package" || header.Filename != filepath.Base(pkgPath) {
+			t.Errorf("wrong package (%s: %s)", header.Filename, b)
+		}
+
+		f, _, err = r.FormFile("packageAsc")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, _ := ioutil.ReadAll(f); string(b) != "signature" {
+			t.Errorf("wrong signature (%s)", b)
+		}
+	}))
+	defer srv.Close()
+
+	if err := Upload(pkgPath, srv.URL+"/", srv.Client()); err != nil {
+		t.Error(err)
+	}
+}
@@ -2,6 +2,7 @@ package file
 
 import (
 	"errors"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -28,3 +29,18 @@ func FindByExtensions(file string, extensions []string) (string, error) {
 
 	return "", ErrNoFileFound
 }
+
+// WriteReader writes the content read from r to file, creating it with given permissions if needed.
+func WriteReader(file string, r io.Reader, perm os.FileMode) error {
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
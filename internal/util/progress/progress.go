@@ -0,0 +1,86 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// refreshRate is the minimum delay between two renders
+const refreshRate = 100 * time.Millisecond
+
+// Bar is an io.Writer displaying the progress of a transfer
+type Bar struct {
+	out        io.Writer
+	name       string
+	current    int64
+	total      int64
+	lastRender time.Time
+}
+
+// New create a progress bar for a transfer of total bytes, starting at current
+// the bar is only displayed if stderr is a terminal
+// total may be negative if unknown
+func New(name string, current, total int64) *Bar {
+	out := ioutil.Discard
+	if IsTerminal(os.Stderr) {
+		out = os.Stderr
+	}
+
+	return &Bar{out: out, name: name, current: current, total: total}
+}
+
+func (b *Bar) Write(p []byte) (int, error) {
+	b.current += int64(len(p))
+
+	if time.Since(b.lastRender) >= refreshRate {
+		b.render()
+	}
+
+	return len(p), nil
+}
+
+// Finish render the final state of the bar
+func (b *Bar) Finish() {
+	b.render()
+	fmt.Fprintln(b.out)
+}
+
+func (b *Bar) render() {
+	b.lastRender = time.Now()
+
+	if b.total <= 0 {
+		fmt.Fprintf(b.out, "\r%s %s", b.name, formatSize(b.current))
+		return
+	}
+
+	fmt.Fprintf(b.out, "\r%s %3d%% (%s / %s)", b.name, b.current*100/b.total,
+		formatSize(b.current), formatSize(b.total))
+}
+
+// IsTerminal determinate if given file is a terminal
+func IsTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
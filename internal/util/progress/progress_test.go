@@ -0,0 +1,36 @@
+package progress
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestBar(t *testing.T) {
+	var out bytes.Buffer
+	b := &Bar{out: &out, name: "foo.pkg", current: 512, total: 2048}
+
+	if _, err := b.Write(make([]byte, 512)); err != nil {
+		t.Error(err)
+	}
+	b.Finish()
+
+	if !strings.Contains(out.String(), "foo.pkg  50% (1.0 KiB / 2.0 KiB)") {
+		t.Errorf("wrong progress output: %q", out.String())
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := map[int64]string{
+		12:              "12 B",
+		1024:            "1.0 KiB",
+		1536:            "1.5 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+	}
+
+	for size, expected := range tests {
+		if got := formatSize(size); got != expected {
+			t.Errorf("wrong size (got %s want %s)", got, expected)
+		}
+	}
+}
@@ -1,5 +1,11 @@
 package util
 
+import (
+	"bytes"
+
+	"gopkg.in/yaml.v3"
+)
+
 // Contains checks if a specific string (needle) exist in a specific slice (haystack)
 func Contains(haystack []string, needle string) bool {
 	for _, a := range haystack {
@@ -9,3 +15,19 @@ func Contains(haystack []string, needle string) bool {
 	}
 	return false
 }
+
+// EncodeYAML returns the YAML encoding of v, indented with two spaces
+func EncodeYAML(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
@@ -0,0 +1,164 @@
+package watch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+
+	make2 "github.com/go-pkg-org/gopkg/internal/make"
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+	"github.com/rs/zerolog/log"
+)
+
+// Result is the upstream status of a control directory
+type Result struct {
+	Directory  string `json:"directory"`
+	ImportPath string `json:"import_path,omitempty"`
+	// CurrentVersion is the latest release version of the changelog
+	CurrentVersion string `json:"current_version,omitempty"`
+	// UpstreamVersion & Tag are the latest upstream release (empty if no tags matched)
+	UpstreamVersion string `json:"upstream_version,omitempty"`
+	Tag             string `json:"tag,omitempty"`
+	// Outdated is true if the upstream version is greater than the current one
+	Outdated bool `json:"outdated"`
+	// Error is set when the control directory could not be checked
+	Error string `json:"error,omitempty"`
+}
+
+// Check checks the upstream status of the control directory located at directory
+// httpClient is used to resolve the upstream remote when the metadata does not record it
+func Check(directory string, httpClient *http.Client) Result {
+	res := Result{Directory: directory}
+	if err := check(&res, httpClient); err != nil {
+		res.Error = err.Error()
+	}
+
+	return res
+}
+
+func check(res *Result, httpClient *http.Client) error {
+	m, c, err := pkg.ReadCtrlDirectory(res.Directory)
+	if err != nil {
+		return err
+	}
+	res.ImportPath = m.ImportPath
+
+	lastRelease, err := c.LastRelease()
+	if err != nil {
+		return err
+	}
+	res.CurrentVersion = lastRelease.Version
+
+	var w pkg.Watch
+	if m.Watch != nil {
+		w = *m.Watch
+	}
+
+	remote := w.Remote
+	if remote == "" {
+		remote, err = make2.GetRemote(m.ImportPath, httpClient)
+		if err != nil {
+			return err
+		}
+	}
+
+	tags, err := listTags(remote)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		version, ok, err := w.TagVersion(tag)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		// the upstream versions are compared as initial releases
+		if err := pkg.ValidateVersion(version + "-1"); err != nil {
+			log.Debug().Str("tag", tag).Str("version", version).Msg("Ignoring tag with invalid version")
+			continue
+		}
+
+		if res.UpstreamVersion == "" || pkg.CompareVersions(version+"-1", res.UpstreamVersion+"-1") > 0 {
+			res.UpstreamVersion = version
+			res.Tag = tag
+		}
+	}
+
+	res.Outdated = res.UpstreamVersion != "" && pkg.CompareVersions(res.UpstreamVersion+"-1", res.CurrentVersion) > 0
+	return nil
+}
+
+// listTags returns the tags of the given git remote
+func listTags(remote string) ([]string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", "ls-remote", "--tags", remote)
+	cmd.Stderr = &stderr
+
+	log.Trace().Msgf("Executing `%s`", cmd.String())
+	b, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error while listing tags of %s: %s (%s)", remote, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var tags []string
+	seen := map[string]bool{}
+	for _, line := range strings.Split(string(b), "\n") {
+		parts := strings.Fields(line)
+		if len(parts) != 2 {
+			continue
+		}
+
+		// annotated tags are listed twice: the tag & the commit it points to (^{})
+		tag := strings.TrimSuffix(strings.TrimPrefix(parts[1], "refs/tags/"), "^{}")
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags, nil
+}
+
+// Write writes the results to w, either as a table or as JSON
+func Write(w io.Writer, results []Result, jsonOutput bool) error {
+	if jsonOutput {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DIRECTORY\tCURRENT\tUPSTREAM\tSTATUS")
+	for _, res := range results {
+		status := "up to date"
+		switch {
+		case res.Error != "":
+			status = "error: " + res.Error
+		case res.UpstreamVersion == "":
+			status = "no release tags"
+		case res.Outdated:
+			status = "outdated"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", res.Directory, orNone(res.CurrentVersion), orNone(res.UpstreamVersion), status)
+	}
+
+	return tw.Flush()
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
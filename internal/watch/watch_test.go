@@ -0,0 +1,123 @@
+package watch
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-pkg-org/gopkg/internal/pkg"
+)
+
+func TestCheck(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "gopkg_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	upstreamDir := filepath.Join(dir, "upstream")
+	if err := os.MkdirAll(upstreamDir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"-c", "user.name=John Doe", "-c", "user.email=john@doe.com", "commit", "-q", "--allow-empty", "-m", "initial"},
+		{"tag", "v1.0.0"},
+		{"tag", "v1.10.0-rc1"},
+		{"tag", "-a", "-m", "release", "v1.9.0"},
+		{"tag", "nightly"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = upstreamDir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s (%s)", args, err, out)
+		}
+	}
+
+	ctrlDir := filepath.Join(dir, "github.com-foo-bar")
+	m := pkg.ControlMeta{
+		ImportPath: "github.com/foo/bar",
+		Watch:      &pkg.Watch{Remote: upstreamDir, VersionMangle: []string{"s/-rc/~rc/"}},
+	}
+	if err := pkg.CreateCtrlDirectory(ctrlDir, "1.0.0", "John Doe <john@doe.com>", m); err != nil {
+		t.Fatal(err)
+	}
+
+	res := Check(ctrlDir, nil)
+	if res.Error != "" {
+		t.Fatal(res.Error)
+	}
+	// 1.10.0~rc1 > 1.9.0 > 1.0.0
+	if res.UpstreamVersion != "1.10.0~rc1" || res.Tag != "v1.10.0-rc1" || !res.Outdated {
+		t.Errorf("wrong result: %+v", res)
+	}
+	if res.CurrentVersion != "1.0.0-1" || res.ImportPath != "github.com/foo/bar" {
+		t.Errorf("wrong result: %+v", res)
+	}
+
+	// only final releases
+	m.Watch.TagPattern = `^v([0-9.]+)$`
+	if err := pkg.WriteCtrlMetadata(ctrlDir, m); err != nil {
+		t.Fatal(err)
+	}
+	if res := Check(ctrlDir, nil); res.UpstreamVersion != "1.9.0" || res.Tag != "v1.9.0" || !res.Outdated {
+		t.Errorf("wrong result: %+v", res)
+	}
+
+	// up to date
+	m.Watch.TagPattern = `^v(1\.0\..*)$`
+	if err := pkg.WriteCtrlMetadata(ctrlDir, m); err != nil {
+		t.Fatal(err)
+	}
+	if res := Check(ctrlDir, nil); res.UpstreamVersion != "1.0.0" || res.Outdated {
+		t.Errorf("wrong result: %+v", res)
+	}
+
+	if res := Check(filepath.Join(dir, "missing"), nil); res.Error == "" {
+		t.Error("Check should have failed")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	results := []Result{
+		{Directory: "foo", CurrentVersion: "1.0.0-1", UpstreamVersion: "1.1.0", Tag: "v1.1.0", Outdated: true},
+		{Directory: "bar", CurrentVersion: "1.0.0-1", UpstreamVersion: "1.0.0", Tag: "v1.0.0"},
+		{Directory: "baz", Error: "no such file or directory"},
+	}
+
+	var b bytes.Buffer
+	if err := Write(&b, results, false); err != nil {
+		t.Fatal(err)
+	}
+	expected := `DIRECTORY  CURRENT  UPSTREAM  STATUS
+foo        1.0.0-1  1.1.0     outdated
+bar        1.0.0-1  1.0.0     up to date
+baz        -        -         error: no such file or directory
+`
+	if b.String() != expected {
+		t.Errorf("wrong output:\n%s", b.String())
+	}
+
+	b.Reset()
+	if err := Write(&b, results, true); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []Result
+	if err := json.Unmarshal(b.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 3 || decoded[0] != results[0] || !strings.Contains(b.String(), `"outdated": true`) {
+		t.Errorf("wrong output:\n%s", b.String())
+	}
+}